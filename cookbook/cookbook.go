@@ -14,6 +14,8 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/appbricks/cloud-builder/semver"
+	"github.com/appbricks/cloud-builder/terraform"
 	"github.com/gobuffalo/packr/v2"
 	"github.com/mevansam/gocloud/provider"
 	"github.com/mevansam/goutils/logger"
@@ -31,8 +33,14 @@ type Cookbook struct {
 
 	path,
 	tfPluginPath,
+	tfBinPath,
 	tfCLIPath string
 
+	// versions of the engine clis keyed by the
+	// cli path. a version is nil if it could not
+	// be determined.
+	cliVersions map[string]*semver.Version
+
 	files []string
 	
 	// nested map [recipe_name][iaas_name]
@@ -55,6 +63,10 @@ type CookbookRecipeInfo struct {
 
 	IsBastion bool
 	IaaSList  []provider.CloudProvider
+
+	// json schemas of the recipe's input
+	// forms keyed by iaas name
+	InputSchemas map[string]*terraform.JSONSchema
 }
 
 type CookbookMetadata struct {
@@ -67,9 +79,27 @@ type CookbookMetadata struct {
 
 	EnvVars [][]string `yaml:"env-args"`
 
+	// infrastructure as code engine used to launch the
+	// cookbook's recipes and the registry host of its
+	// providers. the engine defaults to terraform and
+	// the registry host to the engine's registry.
+	Engine         string `yaml:"engine"`
+	PluginRegistry string `yaml:"plugin-registry"`
+
 	Imported bool
 	Recipes  []string
 
+	// version of the cookbook's engine cli
+	EngineVersion string
+
+	// the cookbook's terraform version constraint
+	// is not satisfied by the engine cli
+	TerraformVersionMismatch bool
+
+	// latest compatible version of the cookbook in
+	// the repository last checked for updates
+	LatestVersion string
+
 	cookbookPath string
 }
 
@@ -110,6 +140,8 @@ func NewCookbook(
 
 		cookbooks:     make(map[string]*CookbookMetadata),
 		repoTimestamp: ts,
+
+		cliVersions: make(map[string]*semver.Version),
 	}
 
 	info, err := os.Stat(c.path)
@@ -131,13 +163,10 @@ func NewCookbook(
 
 		// embedded cookbook plugin path
 		c.tfPluginPath = filepath.Join(c.path, "bin", "plugins")
-		if runtime.GOOS == "windows" {
-			// windows cli
-			c.tfCLIPath = filepath.Join(c.path, "bin", "terraform.exe")
-		} else {
-			// *nix cli
-			c.tfCLIPath = filepath.Join(c.path, "bin", "terraform")
-		}
+		// embedded cookbook engine clis path
+		c.tfBinPath = filepath.Join(c.path, "bin")
+		// default engine cli
+		c.tfCLIPath = c.cliPath(terraform.TerraformEngine)
 
 		// Retrieve cookbook file list by walking
 		// the extracted cookbook's directory tree
@@ -195,8 +224,9 @@ func (c *Cookbook) addRecipeMetadata(cookbookRoot, recipesPath string) error {
 			r  Recipe
 			rr map[string]Recipe
 
-			data []byte
-			cm   *CookbookMetadata		
+			data   []byte
+			cm     *CookbookMetadata
+			engine *terraform.Engine
 		)
 
 		if match := recipePathMatcher.Match([]byte(file)); match {
@@ -217,6 +247,14 @@ func (c *Cookbook) addRecipeMetadata(cookbookRoot, recipesPath string) error {
 				}
 				
 				recipeKey = metadata.CookbookName + ":" + recipeName
+				if engine, err = terraform.LookupEngine(metadata.Engine); err != nil {
+					return err
+				}
+
+				// retrieve the engine cli's version before locking
+				// as it may need to be determined by running the cli
+				cliPath := c.cliPath(engine)
+				cliVersion := c.cliVersion(cliPath)
 
 				c.mx.Lock()
 
@@ -224,6 +262,10 @@ func (c *Cookbook) addRecipeMetadata(cookbookRoot, recipesPath string) error {
 				if cm, ok = c.cookbooks[metadata.CookbookName]; !ok {
 					metadata.Imported = (c.path != cookbookRoot)
 					metadata.cookbookPath = cookbookRoot
+					if err = c.checkEngineVersion(&metadata, cliPath, cliVersion); err != nil {
+						logger.WarnMessage("Recipes of the cookbook may fail to launch: %s", err.Error())
+						metadata.TerraformVersionMismatch = true
+					}
 					cm = &metadata
 					c.cookbooks[metadata.CookbookName] = cm
 				}
@@ -253,7 +295,7 @@ func (c *Cookbook) addRecipeMetadata(cookbookRoot, recipesPath string) error {
 					filepath.Join(cookbookRoot, pathSuffix),
 					c.tfPluginPath,
 					filepath.Join(c.workspacePath, "state", cm.CookbookName, pathSuffix),
-					c.tfCLIPath,
+					c.cliPath(engine),
 					filepath.Join(c.workspacePath, "run", cm.CookbookName, pathSuffix),
 					c.repoTimestamp,
 					metadata.CookbookName,
@@ -315,6 +357,8 @@ func (c *Cookbook) ImportCookbook(cookbookPath string) (err error) {
 		zipFile *os.File
 
 		data  []byte
+
+		engine *terraform.Engine
 	)
 
 	libraryPath := filepath.Join(
@@ -344,16 +388,8 @@ func (c *Cookbook) ImportCookbook(cookbookPath string) (err error) {
 		return err
 	}
 
-	// validate cookbook structure
-	invalidError := fmt.Errorf("invalid cookbook structure")
-	if fi, err = os.Stat(filepath.Join(unzipPath, "bin", "plugins", "registry.terraform.io")); os.IsNotExist(err) || !fi.IsDir() {
-		return invalidError
-	}
-	if fi, err = os.Stat(filepath.Join(unzipPath, "recipes")); os.IsNotExist(err) || !fi.IsDir() {
-		return invalidError
-	}
-
 	// read cookbook metadata
+	invalidError := fmt.Errorf("invalid cookbook structure")
 	metadata := CookbookMetadata{}
 	if data, err = os.ReadFile(filepath.Join(unzipPath, "METADATA")); err != nil {
 		return err
@@ -361,6 +397,28 @@ func (c *Cookbook) ImportCookbook(cookbookPath string) (err error) {
 	if err = yaml.Unmarshal(data, &metadata); err != nil {
 		return err
 	}
+	if engine, err = terraform.LookupEngine(metadata.Engine); err != nil {
+		return err
+	}
+
+	// validate cookbook structure
+	if fi, err = os.Stat(filepath.Join(unzipPath, "bin", "plugins", metadata.registryHost(engine))); os.IsNotExist(err) || !fi.IsDir() {
+		return invalidError
+	}
+	if fi, err = os.Stat(filepath.Join(unzipPath, "recipes")); os.IsNotExist(err) || !fi.IsDir() {
+		return invalidError
+	}
+	// the engine cli must be shipped with the cookbook
+	// if it is not available in the embedded cookbook
+	cliPath := c.cliPath(engine)
+	if _, err = os.Stat(cliPath); os.IsNotExist(err) {
+		cliPath = filepath.Join(unzipPath, "bin", engine.BinaryFileName())
+		if _, err = os.Stat(cliPath); os.IsNotExist(err) {
+			return fmt.Errorf(
+				"cookbook uses the '%s' engine but does not include its cli '%s'",
+				engine.Name, engine.BinaryFileName())
+		}
+	}
 	if len(metadata.CookbookName) == 0 ||
 		len(metadata.CookbookVersion) == 0 ||
 		len(metadata.TerraformVersion) == 0 ||
@@ -374,6 +432,9 @@ func (c *Cookbook) ImportCookbook(cookbookPath string) (err error) {
 	if runtime.GOARCH != metadata.TargetOsArch {
 		return fmt.Errorf("cookbook does not support local system os' architecture")
 	}
+	if err = c.checkEngineVersion(&metadata, cliPath, c.cliVersion(cliPath)); err != nil {
+		return err
+	}
 
 	// import cookbook
 	importPath := filepath.Join(
@@ -426,14 +487,45 @@ func (c *Cookbook) importCookbook(cookbookPath string) error {
 		err error
 
 		vbytes []byte
+		data   []byte
+
+		engine *terraform.Engine
 	)
 
 	if vbytes, err = os.ReadFile(filepath.Join(cookbookPath, "CURRENT")); err != nil {
 		return err
 	}
 	versionedPath := filepath.Join(cookbookPath, strings.TrimSpace(string(vbytes[:])))
-	pluginSrcPath := filepath.Join(versionedPath, "bin", "plugins", "registry.terraform.io")
-	pluginDestPath := filepath.Join(c.path, "bin", "plugins", "registry.terraform.io")
+
+	metadata := CookbookMetadata{}
+	if data, err = os.ReadFile(filepath.Join(versionedPath, "METADATA")); err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(data, &metadata); err != nil {
+		return err
+	}
+	if engine, err = terraform.LookupEngine(metadata.Engine); err != nil {
+		return err
+	}
+
+	// add the engine cli to the embedded cookbook
+	// if the imported cookbook includes it
+	cliSrcPath := filepath.Join(versionedPath, "bin", engine.BinaryFileName())
+	cliDestPath := c.cliPath(engine)
+	if _, err = os.Stat(cliDestPath); os.IsNotExist(err) {
+		if _, err = os.Stat(cliSrcPath); err == nil {
+			if err = utils.CopyFiles(cliSrcPath, cliDestPath, 1024); err != nil {
+				os.Remove(cliDestPath)
+				return err
+			}
+			if err = os.Chmod(cliDestPath, 0755); err != nil {
+				return err
+			}
+		}
+	}
+
+	pluginSrcPath := filepath.Join(versionedPath, "bin", "plugins", metadata.registryHost(engine))
+	pluginDestPath := filepath.Join(c.path, "bin", "plugins", metadata.registryHost(engine))
 
 	errs := []error{}
 
@@ -482,6 +574,110 @@ func (c *Cookbook) importCookbook(cookbookPath string) error {
 	return nil
 }
 
+// out: path of the given engine's cli
+func (c *Cookbook) cliPath(engine *terraform.Engine) string {
+	return filepath.Join(c.tfBinPath, engine.BinaryFileName())
+}
+
+// out: the version of the cli at the given path or nil
+//      if it could not be determined. the cli is run
+//      without holding the cookbook's lock so the caller
+//      must not hold it.
+func (c *Cookbook) cliVersion(cliPath string) *semver.Version {
+
+	var (
+		err     error
+		ok      bool
+		version *semver.Version
+	)
+
+	c.mx.Lock()
+	version, ok = c.cliVersions[cliPath]
+	c.mx.Unlock()
+
+	if !ok {
+		if version, err = terraform.GetCLIVersion(cliPath, c.path); err != nil {
+			logger.WarnMessage("Version checks of cookbooks will fail: %s", err.Error())
+		}
+		c.mx.Lock()
+		c.cliVersions[cliPath] = version
+		c.mx.Unlock()
+	}
+	return version
+}
+
+// checks that the given version of the engine cli at
+// the given path satisfies the terraform version
+// constraint of the cookbook. the version is nil if
+// it is not known.
+func (c *Cookbook) checkEngineVersion(
+	metadata *CookbookMetadata, 
+	cliPath string, 
+	version *semver.Version,
+) error {
+
+	var (
+		err error
+
+		constraint *semver.VersionConstraint
+	)
+
+	if version != nil {
+		metadata.EngineVersion = version.String()
+	}
+	if len(metadata.TerraformVersion) == 0 {
+		return nil
+	}
+	if constraint, err = metadata.EngineVersionConstraint(); err != nil {
+		return fmt.Errorf(
+			"cookbook '%s' has an invalid terraform version: %s",
+			metadata.CookbookName, err.Error())
+	}
+	if version == nil {
+		return fmt.Errorf(
+			"cookbook '%s' requires version '%s' but the version of the cli at '%s' is not known",
+			metadata.CookbookName, constraint, cliPath)
+	}
+	if !constraint.Check(version) {
+		return fmt.Errorf(
+			"cookbook '%s' requires version '%s' but the cli at '%s' is version '%s'",
+			metadata.CookbookName, constraint, cliPath, version)
+	}
+	return nil
+}
+
+// out: the constraint the version of the cookbook's engine
+//      cli must satisfy. cookbooks are built with the exact
+//      version of the cli they were tested with so a bare
+//      version allows any patch release of the same minor
+//      version i.e. "1.5.7" allows "1.5.2" but not "1.6.0".
+func (cm *CookbookMetadata) EngineVersionConstraint() (*semver.VersionConstraint, error) {
+
+	if version, err := semver.ParseVersion(cm.TerraformVersion); err == nil {
+		return semver.ParseVersionConstraint(
+			fmt.Sprintf("~> %d.%d.0", version.Major, version.Minor))
+	}
+	return semver.ParseVersionConstraint(cm.TerraformVersion)
+}
+
+// out: the registry host of the cookbook's providers
+func (cm *CookbookMetadata) registryHost(engine *terraform.Engine) string {
+	if len(cm.PluginRegistry) > 0 {
+		return cm.PluginRegistry
+	}
+	return engine.RegistryHost
+}
+
+// out: the version of the embedded cookbook's terraform
+//      cli or an empty string if it is not known
+func (c *Cookbook) TerraformVersion() string {
+
+	if version := c.cliVersion(c.tfCLIPath); version != nil {
+		return version.String()
+	}
+	return ""
+}
+
 func (c *Cookbook) GetCookbook(name string) *CookbookMetadata {
 	return c.cookbooks[name]
 }
@@ -630,13 +826,15 @@ func (c *Cookbook) RecipeList() []CookbookRecipeInfo {
 
 	for key, rr = range c.recipes {
 		recipeInfo = CookbookRecipeInfo{
-			IaaSList: []provider.CloudProvider{},
+			IaaSList:     []provider.CloudProvider{},
+			InputSchemas: make(map[string]*terraform.JSONSchema),
 		}
 
 		// add iaas list
 		for iaas, r = range rr {
 			cp, _ := provider.NewCloudProvider(iaas)
 			recipeInfo.IaaSList = append(recipeInfo.IaaSList, cp)
+			recipeInfo.InputSchemas[iaas] = r.InputSchema()
 		}
 		provider.SortCloudProviders(recipeInfo.IaaSList)
 
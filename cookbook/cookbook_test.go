@@ -9,6 +9,7 @@ import (
 	"github.com/gobuffalo/packr/v2"
 
 	"github.com/appbricks/cloud-builder/cookbook"
+	"github.com/appbricks/cloud-builder/semver"
 	"github.com/mevansam/goutils/logger"
 	"github.com/mevansam/goutils/run"
 	"github.com/mevansam/goutils/utils"
@@ -48,6 +49,51 @@ var _ = Describe("Cookbook", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("validates the terraform configuration of all recipes", func() {
+
+			if _, err = os.Stat(filepath.Join(workspacePath, "bin", "terraform")); os.IsNotExist(err) {
+				Skip("terraform cli required to validate recipes is not available")
+			}
+
+			// validation must not change the recipes' templates
+			lockFiles := make(map[string][]byte)
+			templateFiles := make(map[string][]string)
+			templateFileNames := func(configPath string) []string {
+				names := []string{}
+				entries, err := os.ReadDir(configPath)
+				Expect(err).NotTo(HaveOccurred())
+				for _, e := range entries {
+					names = append(names, e.Name())
+				}
+				return names
+			}
+			for _, info := range c.RecipeList() {
+				for _, iaas := range info.IaaSList {
+					configPath := c.GetRecipe(info.RecipeKey, iaas.Name()).ConfigPath()
+					lockPath := filepath.Join(configPath, ".terraform.lock.hcl")
+					lockFiles[lockPath], _ = os.ReadFile(lockPath)
+					templateFiles[configPath] = templateFileNames(configPath)
+				}
+			}
+
+			report := c.ValidateRecipes()
+			Expect(report.Invalid()).To(BeEmpty(), report.String())
+			Expect(report.Valid()).To(BeTrue())
+			Expect(len(report.Recipes)).To(BeNumerically(">", 0))
+
+			for lockPath, data := range lockFiles {
+				current, err := os.ReadFile(lockPath)
+				if data == nil {
+					Expect(os.IsNotExist(err)).To(BeTrue(), "lock file '%s' was created", lockPath)
+				} else {
+					Expect(current).To(Equal(data), "lock file '%s' was changed", lockPath)
+				}
+			}
+			for configPath, names := range templateFiles {
+				Expect(templateFileNames(configPath)).To(Equal(names), "templates at '%s' were changed", configPath)
+			}
+		})
+
 		Context("info", func() {
 
 			It("lists all the IaaS's that the Cookbook has recipes for", func() {
@@ -169,6 +215,36 @@ var _ = Describe("Cookbook", func() {
 	})
 })
 
+var _ = Describe("Cookbook Metadata", func() {
+
+	It("allows any patch release of the terraform version the cookbook was built with", func() {
+
+		checks := []struct {
+			terraformVersion string
+			cliVersion       string
+			satisfied        bool
+		}{
+			{"1.5.7", "1.5.7", true},
+			{"1.5.7", "1.5.2", true},
+			{"1.5.7", "1.5.10", true},
+			{"1.5.7", "1.6.0", false},
+			{"1.5.7", "1.4.9", false},
+			{">= 1.3.0, < 1.6.0", "1.5.2", true},
+			{">= 1.3.0, < 1.6.0", "1.6.0", false},
+		}
+		for _, c := range checks {
+			metadata := cookbook.CookbookMetadata{TerraformVersion: c.terraformVersion}
+			constraint, err := metadata.EngineVersionConstraint()
+			Expect(err).NotTo(HaveOccurred())
+			version, err := semver.ParseVersion(c.cliVersion)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(constraint.Check(version)).To(Equal(c.satisfied),
+				"cli version '%s' with terraform version '%s'", c.cliVersion, c.terraformVersion)
+		}
+	})
+})
+
 var _ = Describe("Cookbook Import", func() {
 
 	var (
@@ -264,6 +340,10 @@ func validateCoobookRecipes(c *cookbook.Cookbook, recipeSet map[string][]string)
 		Expect(len(info.IaaSList)).To(Equal(len(iaasSet)))
 		for i, iaas := range iaasSet {
 			Expect(info.IaaSList[i].Name()).To(Equal(iaas))
+
+			schema := info.InputSchemas[iaas]
+			Expect(schema).NotTo(BeNil())
+			Expect(schema.Type).To(Equal("object"))
 		}
 	}
 }
@@ -0,0 +1,69 @@
+package cookbook
+
+import (
+	"fmt"
+	"strings"
+)
+
+/**
+ * Recipe Variable Migration
+ */
+
+// a saved variable value that was migrated
+// to a variable's new name
+type VariableRename struct {
+	From string
+	To   string
+}
+
+// a saved variable value of a deprecated variable
+type VariableDeprecation struct {
+	Name    string
+	Message string
+}
+
+// changes made to a recipe's saved variable values
+// when they were loaded. values of variables renamed
+// via the @renamed_from annotation are moved to the
+// new variable and values of deprecated variables are
+// reported so users can be warned.
+type MigrationReport struct {
+	Renamed    []*VariableRename
+	Deprecated []*VariableDeprecation
+
+	// saved variables that are no longer declared
+	// by the recipe and whose values are dropped
+	Removed []string
+}
+
+func newMigrationReport() *MigrationReport {
+	return &MigrationReport{
+		Renamed:    []*VariableRename{},
+		Deprecated: []*VariableDeprecation{},
+		Removed:    []string{},
+	}
+}
+
+// out: true if any saved values were
+//      migrated, deprecated or removed
+func (mr *MigrationReport) HasChanges() bool {
+	return len(mr.Renamed) > 0 || len(mr.Deprecated) > 0 || len(mr.Removed) > 0
+}
+
+func (mr *MigrationReport) String() string {
+
+	var (
+		out strings.Builder
+	)
+
+	for _, r := range mr.Renamed {
+		out.WriteString(fmt.Sprintf("variable '%s' was renamed to '%s'\n", r.From, r.To))
+	}
+	for _, d := range mr.Deprecated {
+		out.WriteString(fmt.Sprintf("variable '%s' is deprecated: %s\n", d.Name, d.Message))
+	}
+	for _, name := range mr.Removed {
+		out.WriteString(fmt.Sprintf("variable '%s' is no longer used and its value was removed\n", name))
+	}
+	return out.String()
+}
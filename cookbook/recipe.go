@@ -8,6 +8,8 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/appbricks/cloud-builder/terraform"
 	"github.com/mevansam/goforms/config"
@@ -42,6 +44,19 @@ type Recipe interface {
 	GetVariables() []*Variable
 	GetKeyFieldValues() []string
 
+	IsVariableVisible(name string) bool
+	IsVariableRequired(name string) bool
+	VisibleInputFields() ([]*forms.InputField, error)
+
+	ValidateInputs() error
+
+	GetVariableGroups() []*VariableGroup
+	GetInputFieldGroups() ([]*InputFieldGroup, error)
+
+	InputSchema() *terraform.JSONSchema
+
+	MigrationReport() *MigrationReport
+
 	IsBastion() bool
 	ResourceInstanceList() []string
 	ResourceInstanceDataList() []string
@@ -62,6 +77,88 @@ type Variable struct {
 	Name     string  `json:"name"`
 	Value    *string `json:"value"`
 	Optional bool    `json:"optional"`
+
+	// the group of the variable declared via the
+	// variable's @group annotation
+	Group string `json:"group,omitempty"`
+
+	// terraform type constraint of the variable
+	// which is read from the recipe's templates
+	Type string `json:"-"`
+
+	// rules of the variable's validation blocks
+	// which are read from the recipe's templates
+	Validations []*terraform.VariableValidation `json:"-"`
+
+	// default value of the variable used to evaluate
+	// the conditions of other variables when the
+	// variable has not been set
+	Default *string `json:"-"`
+
+	// conditions declared via the variable's @visible_if
+	// and @required_if annotations which determine if
+	// the variable is shown and if it must have a value
+	VisibleIf  *terraform.FieldCondition `json:"-"`
+	RequiredIf *terraform.FieldCondition `json:"-"`
+
+	// deprecation message declared via the variable's
+	// @deprecated annotation. empty if the variable is
+	// not deprecated.
+	Deprecated string `json:"-"`
+}
+
+// validates the variable's value against the
+// rules of the variable's validation blocks
+//
+// out: an error with the validation's error
+//      message if the value is not valid
+func (v *Variable) Validate() error {
+	if v.Value == nil {
+		return nil
+	}
+	return terraform.ValidateVariable(v.Name, v.Type, *v.Value, v.Validations)
+}
+
+// error returned when the values of
+// a recipe's variables are not valid
+type InputValidationError struct {
+	Recipe string
+
+	// the reason each variable that is
+	// not valid was rejected keyed by
+	// the name of the variable
+	Errors map[string]error
+}
+
+func (e *InputValidationError) Error() string {
+
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, 0, len(names))
+	for _, name := range names {
+		messages = append(messages, fmt.Sprintf("%s: %s", name, e.Errors[name].Error()))
+	}
+	return fmt.Sprintf(
+		"inputs of recipe '%s' are not valid: %s",
+		e.Recipe, strings.Join(messages, "; "))
+}
+
+// a named section of a recipe's variables
+type VariableGroup struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Variables   []*Variable `json:"variables"`
+}
+
+// a named section of a recipe's input form
+type InputFieldGroup struct {
+	Name        string
+	Description string
+	Fields      []*forms.InputField
 }
 
 type recipe struct {
@@ -70,6 +167,16 @@ type recipe struct {
 
 	variables map[string]*Variable
 	keyFields []string
+	groups    []*terraform.VariableGroup
+
+	// json schema of the recipe's input form
+	inputSchema *terraform.JSONSchema
+
+	// new names of renamed variables keyed by the
+	// names they were renamed from and the changes
+	// made to saved values when they were loaded
+	renames         map[string]string
+	migrationReport *MigrationReport
 
 	isBastion                bool
 	resourceInstanceList     []string
@@ -128,6 +235,12 @@ func NewRecipe(
 
 		variables: make(map[string]*Variable),
 		keyFields: reader.KeyFields(),
+		groups:    reader.VariableGroups(),
+
+		inputSchema: reader.InputSchema(),
+
+		renames:         reader.VariableRenames(),
+		migrationReport: newMigrationReport(),
 
 		isBastion:                reader.IsBastion(),
 		resourceInstanceList:     reader.ResourceInstanceList(),
@@ -148,11 +261,36 @@ func NewRecipe(
 		recipeIaaS:      recipeIaaS,
 		recipeEnvVars:   recipeEnvVars,
 	}
+	variableTypes := reader.VariableTypes()
+	variableValidations := reader.VariableValidations()
+	fieldConditions := reader.FieldConditions()
+	deprecatedVariables := reader.DeprecatedVariables()
+	variableGroups := make(map[string]string)
+	for _, group := range reader.VariableGroups() {
+		for _, name := range group.Variables {
+			variableGroups[name] = group.Name
+		}
+	}
 	for _, f := range reader.InputForm().InputFields() {
-		recipe.variables[f.Name()] = &Variable{
-			Name:     f.Name(),
-			Optional: f.Optional(),
+		variable := &Variable{
+			Name:        f.Name(),
+			Optional:    f.Optional(),
+			Type:        variableTypes[f.Name()],
+			Group:       variableGroups[f.Name()],
+			Validations: variableValidations[f.Name()],
+			Deprecated:  deprecatedVariables[f.Name()],
+		}
+		if value := f.Value(); value != nil {
+			// the field is not bound so
+			// its value is the default
+			defaultValue := *value
+			variable.Default = &defaultValue
 		}
+		if conditions, exists := fieldConditions[f.Name()]; exists {
+			variable.VisibleIf = conditions.VisibleIf
+			variable.RequiredIf = conditions.RequiredIf
+		}
+		recipe.variables[f.Name()] = variable
 	}
 
 	// Ensure variables are bound
@@ -184,7 +322,7 @@ func (r *recipe) validate() error {
 	}
 	if info.Mode()&0111 == 0 {
 		return fmt.Errorf(
-			"the terraform cli at '%s' is not an executable binary",
+			"the engine cli at '%s' is not an executable binary",
 			r.tfCLIPath)
 	}
 
@@ -378,6 +516,74 @@ func (r *recipe) GetVariables() []*Variable {
 	return variables
 }
 
+// out: the recipe's variables grouped by their @group
+//      annotation in form order. variables that do not
+//      belong to a group are in the unnamed group.
+func (r *recipe) GetVariableGroups() []*VariableGroup {
+
+	groups := make([]*VariableGroup, 0, len(r.groups))
+	for _, g := range r.groups {
+		group := &VariableGroup{
+			Name:        g.Name,
+			Description: g.Description,
+			Variables:   make([]*Variable, 0, len(g.Variables)),
+		}
+		for _, name := range g.Variables {
+			if variable, exists := r.variables[name]; exists {
+				group.Variables = append(group.Variables, variable)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// out: the fields of the recipe's input form grouped
+//      by their variable's @group annotation
+func (r *recipe) GetInputFieldGroups() ([]*InputFieldGroup, error) {
+
+	var (
+		err   error
+		form  forms.InputForm
+		field *forms.InputField
+	)
+
+	if form, err = r.InputForm(); err != nil {
+		return nil, err
+	}
+	groups := make([]*InputFieldGroup, 0, len(r.groups))
+	for _, g := range r.groups {
+		group := &InputFieldGroup{
+			Name:        g.Name,
+			Description: g.Description,
+			Fields:      make([]*forms.InputField, 0, len(g.Variables)),
+		}
+		for _, name := range g.Variables {
+			if field, err = form.GetInputField(name); err != nil {
+				return nil, err
+			}
+			group.Fields = append(group.Fields, field)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// out: the json schema of the recipe's input form
+//      which describes the types, defaults and
+//      constraints of the recipe's variables. the
+//      schema returned is a copy that the caller
+//      may change.
+func (r *recipe) InputSchema() *terraform.JSONSchema {
+	return r.inputSchema.Copy()
+}
+
+// out: the changes made to the recipe's saved
+//      variable values when they were loaded
+func (r *recipe) MigrationReport() *MigrationReport {
+	return r.migrationReport
+}
+
 // out: the recipe config specific key value to use for the recipe target
 func (r *recipe) GetKeyFieldValues() []string {
 
@@ -398,6 +604,102 @@ func (r *recipe) GetKeyFieldValues() []string {
 	return keyValues
 }
 
+// in: name - the name of the variable
+// out: true if the variable's @visible_if condition
+//      holds for the current values of the recipe's
+//      variables or if it does not have a condition
+func (r *recipe) IsVariableVisible(name string) bool {
+
+	variable, exists := r.variables[name]
+	if !exists {
+		return false
+	}
+	return r.evaluateCondition(variable, "visible_if", variable.VisibleIf, true)
+}
+
+// in: name - the name of the variable
+// out: true if the variable is visible and it does
+//      not have a default value or its @required_if
+//      condition holds for the current values of the
+//      recipe's variables
+func (r *recipe) IsVariableRequired(name string) bool {
+
+	variable, exists := r.variables[name]
+	if !exists || !r.IsVariableVisible(name) {
+		return false
+	}
+	if !variable.Optional {
+		return true
+	}
+	return r.evaluateCondition(variable, "required_if", variable.RequiredIf, false)
+}
+
+// out: the fields of the recipe's input form whose
+//      @visible_if conditions hold for the current
+//      values of the recipe's variables
+func (r *recipe) VisibleInputFields() ([]*forms.InputField, error) {
+
+	var (
+		err  error
+		form forms.InputForm
+	)
+
+	if form, err = r.InputForm(); err != nil {
+		return nil, err
+	}
+	fields := []*forms.InputField{}
+	for _, f := range form.InputFields() {
+		if r.IsVariableVisible(f.Name()) {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// evaluates a condition of the given variable against
+// the values of the recipe's variables. a variable
+// that has not been set is evaluated with its default.
+//
+// in: variable - the variable the condition belongs to
+// in: annotation - the annotation that declared the condition
+// in: condition - the condition to evaluate
+// in: result - the result if there is no condition or
+//              it cannot be evaluated
+// out: the result of the condition
+func (r *recipe) evaluateCondition(
+	variable *Variable,
+	annotation string,
+	condition *terraform.FieldCondition,
+	result bool,
+) bool {
+
+	if condition == nil {
+		return result
+	}
+
+	values := make(map[string]string)
+	types := make(map[string]string)
+	for _, ref := range condition.References() {
+		if v, exists := r.variables[ref]; exists {
+			if v.Value != nil {
+				values[ref] = *v.Value
+			} else if v.Default != nil {
+				values[ref] = *v.Default
+			}
+			types[ref] = v.Type
+		}
+	}
+
+	ok, err := condition.Evaluate(values, types)
+	if err != nil {
+		logger.DebugMessage(
+			"Unable to evaluate @%s condition of variable '%s' for recipe '%s': %s",
+			annotation, variable.Name, r.name, err.Error())
+		return result
+	}
+	return ok
+}
+
 // out: true if this is a cloud builder bastion recipe. this means that
 //      the cloud builder apps can use this information to provide
 //      additional services aganst on targets.
@@ -519,6 +821,12 @@ func (r *recipe) Copy() (config.Configurable, error) {
 
 		variables: make(map[string]*Variable),
 		keyFields: r.keyFields,
+		groups:    r.groups,
+
+		inputSchema: r.inputSchema,
+
+		renames:         r.renames,
+		migrationReport: r.migrationReport,
 
 		isBastion:                r.isBastion,
 		resourceInstanceList:     r.resourceInstanceList,
@@ -547,6 +855,14 @@ func (r *recipe) Copy() (config.Configurable, error) {
 				Name:     v.Name,
 				Value:    nil,
 				Optional: v.Optional,
+				Group:    v.Group,
+				Type:     v.Type,
+				Default:  v.Default,
+
+				Validations: v.Validations,
+				VisibleIf:   v.VisibleIf,
+				RequiredIf:  v.RequiredIf,
+				Deprecated:  v.Deprecated,
 			}
 		} else {
 			value := *v.Value
@@ -554,6 +870,14 @@ func (r *recipe) Copy() (config.Configurable, error) {
 				Name:     v.Name,
 				Value:    &value,
 				Optional: v.Optional,
+				Group:    v.Group,
+				Type:     v.Type,
+				Default:  v.Default,
+
+				Validations: v.Validations,
+				VisibleIf:   v.VisibleIf,
+				RequiredIf:  v.RequiredIf,
+				Deprecated:  v.Deprecated,
 			}
 		}
 	}
@@ -562,17 +886,42 @@ func (r *recipe) Copy() (config.Configurable, error) {
 
 func (r *recipe) IsValid() bool {
 
-	for _, v := range r.variables {
+	if err := r.ValidateInputs(); err != nil {
+		logger.TraceMessage(err.Error())
+		return false
+	}
+	return true
+}
+
+// validates the values of the recipe's visible
+// variables against their types and the rules of
+// their validation blocks
+//
+// out: an InputValidationError with the reason each
+//      variable that is not valid was rejected
+func (r *recipe) ValidateInputs() error {
 
-		if !v.Optional && v.Value == nil {
-			logger.TraceMessage(
-				"Required variable '%s' for recipe '%s' has not been set.",
-				v.Name, r.name)
+	failures := make(map[string]error)
+	for _, v := range r.variables {
 
-			return false
+		if !r.IsVariableVisible(v.Name) {
+			// hidden variables are neither
+			// required nor validated
+			continue
+		}
+		if v.Value == nil && r.IsVariableRequired(v.Name) {
+			failures[v.Name] = fmt.Errorf("a value is required")
+		} else if err := v.Validate(); err != nil {
+			failures[v.Name] = err
 		}
 	}
-	return true
+	if len(failures) > 0 {
+		return &InputValidationError{
+			Recipe: r.name,
+			Errors: failures,
+		}
+	}
+	return nil
 }
 
 func (r *recipe) Reset() {
@@ -619,6 +968,13 @@ func (r *recipe) unmarshalVariables(decoder *json.Decoder) error {
 		return err
 	}
 
+	report := newMigrationReport()
+	// names of the variables whose saved values
+	// have been read and the saved names of the
+	// values migrated to renamed variables
+	saved := make(map[string]bool)
+	migrated := make(map[string]string)
+
 	for decoder.More() {
 
 		// read variable
@@ -626,8 +982,68 @@ func (r *recipe) unmarshalVariables(decoder *json.Decoder) error {
 		if err = decoder.Decode(variable); err != nil {
 			return err
 		}
+
+		if _, exists := r.variables[variable.Name]; !exists {
+			if newName, renamed := r.renames[variable.Name]; renamed {
+				if saved[newName] {
+					// the renamed variable has its own saved value
+					report.Removed = append(report.Removed, variable.Name)
+					continue
+				}
+				logger.DebugMessage(
+					"Migrating saved value of recipe variable '%s' to renamed variable '%s'.",
+					variable.Name, newName)
+
+				report.Renamed = append(report.Renamed, &VariableRename{From: variable.Name, To: newName})
+				migrated[newName] = variable.Name
+				variable.Name = newName
+				variable.Optional = r.variables[newName].Optional
+
+			} else {
+				report.Removed = append(report.Removed, variable.Name)
+			}
+
+		} else if oldName, exists := migrated[variable.Name]; exists {
+			// a saved value of the renamed variable
+			// replaces the value migrated to it
+			for i, rename := range report.Renamed {
+				if rename.To == variable.Name {
+					report.Renamed = append(report.Renamed[:i], report.Renamed[i+1:]...)
+					break
+				}
+			}
+			report.Removed = append(report.Removed, oldName)
+			delete(migrated, variable.Name)
+		}
+
+		if v, exists := r.variables[variable.Name]; exists {
+			// group, type, validations, default, conditions and
+			// deprecation are read from the recipe's templates
+			variable.Group = v.Group
+			variable.Type = v.Type
+			variable.Validations = v.Validations
+			variable.Default = v.Default
+			variable.VisibleIf = v.VisibleIf
+			variable.RequiredIf = v.RequiredIf
+			variable.Deprecated = v.Deprecated
+		}
 		r.variables[variable.Name] = variable
+		saved[variable.Name] = true
+	}
+
+	for _, v := range r.variables {
+		if saved[v.Name] && v.Value != nil && len(v.Deprecated) > 0 {
+			logger.WarnMessage(
+				"Recipe '%s' has a value for deprecated variable '%s': %s",
+				r.name, v.Name, v.Deprecated)
+
+			report.Deprecated = append(report.Deprecated, &VariableDeprecation{Name: v.Name, Message: v.Deprecated})
+		}
 	}
+	sort.Slice(report.Deprecated, func(i, j int) bool {
+		return report.Deprecated[i].Name < report.Deprecated[j].Name
+	})
+	r.migrationReport = report
 
 	// read array close bracket
 	_, err = utils.ReadJSONDelimiter(decoder, utils.JsonArrayEndDelim)
@@ -228,6 +228,24 @@ var _ = Describe("Recipe", func() {
 				}
 			})
 
+			It("returns a copy of its input schema", func() {
+
+				schema := r.InputSchema()
+				Expect(schema.Properties["test_input_3"].Sensitive).To(BeTrue())
+				Expect(schema.Properties["test_input_3"].Default).To(BeNil())
+
+				schema.Title = "changed"
+				schema.Required[0] = "changed"
+				schema.Properties["test_input_1"].Enum[0] = "changed"
+				delete(schema.Properties, "test_input_2")
+
+				schema = r.InputSchema()
+				Expect(schema.Title).To(Equal("basic/aws"))
+				Expect(schema.Required[0]).To(Equal("test_input_5"))
+				Expect(schema.Properties["test_input_1"].Enum[0]).To(Equal("aa"))
+				Expect(schema.Properties).To(HaveKey("test_input_2"))
+			})
+
 			It("returns a target key from recipe variables", func() {
 
 				form, err = r.InputForm()
@@ -462,6 +480,214 @@ var _ = Describe("Recipe", func() {
 			})
 		})
 	})
+
+	Describe("grouped variables", func() {
+
+		BeforeEach(func() {
+
+			testRecipePath, err = filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/grouped", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			r, err = cookbook.NewRecipe("grouped", "aws", testRecipePath, "", "", "", "", "", "", "", "", [][]string{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("exposes the groups of the variables and input fields", func() {
+
+			variable, exists := r.GetVariable("subnet_cidr")
+			Expect(exists).To(BeTrue())
+			Expect(variable.Group).To(Equal("Network"))
+
+			groups := r.GetVariableGroups()
+			Expect(len(groups)).To(Equal(4))
+			Expect(groups[1].Name).To(Equal("Credentials"))
+			Expect(len(groups[1].Variables)).To(Equal(2))
+			Expect(groups[1].Variables[0].Name).To(Equal("admin_user"))
+			Expect(groups[1].Variables[1].Name).To(Equal("admin_password"))
+
+			fieldGroups, err := r.GetInputFieldGroups()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(fieldGroups)).To(Equal(4))
+			Expect(fieldGroups[0].Name).To(Equal("Network"))
+			Expect(fieldGroups[0].Description).To(Equal("Network of the deployment"))
+			Expect(fieldGroups[0].Fields[0].Name()).To(Equal("vpc_cidr"))
+			Expect(fieldGroups[0].Fields[1].Name()).To(Equal("subnet_cidr"))
+
+			form, err := r.InputForm()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(form.SetFieldValue("admin_password", "secret")).To(Succeed())
+
+			data, err := json.Marshal(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`"name":"admin_password","value":"secret","optional":false,"group":"Credentials"`))
+
+			copy, err := r.Copy()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(copy.(cookbook.Recipe).GetVariableGroups()[3].Name).To(Equal("VPN"))
+		})
+	})
+
+	Describe("renamed and deprecated variables", func() {
+
+		BeforeEach(func() {
+
+			testRecipePath, err = filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/renamed", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			r, err = cookbook.NewRecipe("renamed", "aws", testRecipePath, "", "", "", "", "", "", "", "", [][]string{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("migrates saved values of renamed variables and reports deprecated variables", func() {
+
+			Expect(r.MigrationReport().HasChanges()).To(BeFalse())
+
+			err = json.Unmarshal([]byte(`{"variables":[
+				{"name":"name","value":"app","optional":false},
+				{"name":"region_name","value":"us-west-2","optional":true},
+				{"name":"size","value":"t3.small","optional":true},
+				{"name":"instance_type","value":"t3.large","optional":true},
+				{"name":"legacy_size","value":"large","optional":true},
+				{"name":"unknown","value":"value","optional":true}
+			]}`), r)
+			Expect(err).NotTo(HaveOccurred())
+
+			value, err := r.GetValue("region")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*value).To(Equal("us-west-2"))
+			value, err = r.GetValue("instance_type")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*value).To(Equal("t3.large"))
+
+			_, exists := r.GetVariable("region_name")
+			Expect(exists).To(BeFalse())
+			_, exists = r.GetVariable("size")
+			Expect(exists).To(BeFalse())
+
+			report := r.MigrationReport()
+			Expect(report.HasChanges()).To(BeTrue())
+			Expect(report.Renamed).To(Equal([]*cookbook.VariableRename{
+				{From: "region_name", To: "region"},
+			}))
+			Expect(report.Deprecated).To(Equal([]*cookbook.VariableDeprecation{
+				{Name: "legacy_size", Message: "use instance_type to size the deployment"},
+			}))
+			Expect(report.Removed).To(Equal([]string{"size", "unknown"}))
+		})
+
+		It("reports removed variables of a recipe that no longer has any variables", func() {
+
+			testRecipePath, err = filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/removed", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			r, err = cookbook.NewRecipe("removed", "aws", testRecipePath, "", "", "", "", "", "", "", "", [][]string{})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = json.Unmarshal([]byte(`{"variables":[
+				{"name":"name","value":"app","optional":false},
+				{"name":"region_name","value":"us-west-2","optional":true}
+			]}`), r)
+			Expect(err).NotTo(HaveOccurred())
+
+			report := r.MigrationReport()
+			Expect(report.HasChanges()).To(BeTrue())
+			Expect(report.Renamed).To(BeEmpty())
+			Expect(report.Deprecated).To(BeEmpty())
+			Expect(report.Removed).To(Equal([]string{"name", "region_name"}))
+		})
+	})
+
+	Describe("variable validation", func() {
+
+		BeforeEach(func() {
+
+			testRecipePath, err = filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/validated", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			r, err = cookbook.NewRecipe("validated", "aws", testRecipePath, "", "", "", "", "", "", "", "", [][]string{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the reason each variable that is not valid was rejected", func() {
+
+			form, err := r.InputForm()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = r.ValidateInputs()
+			Expect(err).To(MatchError("inputs of recipe 'validated/aws' are not valid: name: a value is required"))
+
+			Expect(form.SetFieldValue("name", "My App")).To(Succeed())
+			Expect(form.SetFieldValue("instance_type", "m5.large")).To(Succeed())
+			Expect(r.IsValid()).To(BeFalse())
+
+			err = r.ValidateInputs()
+			validationErr, ok := err.(*cookbook.InputValidationError)
+			Expect(ok).To(BeTrue())
+			Expect(len(validationErr.Errors)).To(Equal(2))
+			Expect(validationErr.Errors["name"]).To(MatchError("The name must start with a letter and contain only lowercase letters, digits and dashes."))
+			Expect(validationErr.Errors["instance_type"]).To(MatchError("The instance type must be one of t3.micro or t3.small."))
+
+			Expect(form.SetFieldValue("name", "my-app")).To(Succeed())
+			Expect(form.SetFieldValue("instance_type", "t3.small")).To(Succeed())
+			Expect(r.ValidateInputs()).To(Succeed())
+			Expect(r.IsValid()).To(BeTrue())
+		})
+	})
+
+	Describe("conditional variables", func() {
+
+		BeforeEach(func() {
+
+			testRecipePath, err = filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/conditional", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			r, err = cookbook.NewRecipe("conditional", "aws", testRecipePath, "", "", "", "", "", "", "", "", [][]string{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("shows and requires variables based on the values of other variables", func() {
+
+			visibleFieldNames := func() []string {
+				fields, err := r.VisibleInputFields()
+				Expect(err).NotTo(HaveOccurred())
+				names := []string{}
+				for _, f := range fields {
+					names = append(names, f.Name())
+				}
+				return names
+			}
+
+			// the hidden openvpn certificate is not required
+			Expect(visibleFieldNames()).To(Equal([]string{"vpn_type", "wireguard_port", "enable_dns", "dns_domain"}))
+			Expect(r.IsVariableVisible("openvpn_cert")).To(BeFalse())
+			Expect(r.IsVariableRequired("openvpn_cert")).To(BeFalse())
+			Expect(r.IsVariableRequired("dns_domain")).To(BeFalse())
+			Expect(r.IsValid()).To(BeTrue())
+
+			form, err := r.InputForm()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(form.SetFieldValue("vpn_type", "openvpn")).To(Succeed())
+			Expect(visibleFieldNames()).To(Equal([]string{"vpn_type", "openvpn_cert", "enable_dns", "dns_domain"}))
+			Expect(r.IsVariableRequired("openvpn_cert")).To(BeTrue())
+			Expect(r.IsValid()).To(BeFalse())
+
+			Expect(form.SetFieldValue("openvpn_cert", "cert")).To(Succeed())
+			Expect(r.IsValid()).To(BeTrue())
+
+			Expect(form.SetFieldValue("enable_dns", "true")).To(Succeed())
+			Expect(r.IsVariableRequired("dns_domain")).To(BeTrue())
+			Expect(r.IsValid()).To(BeFalse())
+
+			Expect(form.SetFieldValue("dns_domain", "vpn.example.com")).To(Succeed())
+			Expect(r.IsValid()).To(BeTrue())
+
+			// conditions are carried over to copies
+			copy, err := r.Copy()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(copy.(cookbook.Recipe).IsVariableVisible("wireguard_port")).To(BeFalse())
+		})
+	})
 })
 
 const recipeInputDataReferenceOutput = term.BOLD + `Recipe 'Basic' for AWS
@@ -0,0 +1,401 @@
+package cookbook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/appbricks/cloud-builder/semver"
+	"github.com/mevansam/goutils/logger"
+)
+
+/**
+ * Cookbook Repository
+ */
+
+// name of a repository's index file if the
+// repository location is not the index itself
+const RepositoryIndexFileName = "index.yaml"
+
+// a repository of cookbook distributions described by
+// an index that is read over http(s) or from a local
+// directory
+type Repository struct {
+	// url or local path of the repository's index
+	indexLocation string
+
+	httpClient *http.Client
+}
+
+// index of the cookbooks available in a repository
+type RepositoryIndex struct {
+	Cookbooks []*RepositoryCookbook `yaml:"cookbooks"`
+}
+
+// a cookbook available in a repository
+type RepositoryCookbook struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+
+	Versions []*RepositoryCookbookVersion `yaml:"versions"`
+}
+
+// a distribution of a cookbook version for an os and
+// architecture. the url of the distribution's zip file
+// may be relative to the location of the index.
+type RepositoryCookbookVersion struct {
+	Version      string `yaml:"version"`
+	TargetOsName string `yaml:"target-os-name"`
+	TargetOsArch string `yaml:"target-os-arch"`
+
+	// sha256 checksum of the distribution's
+	// zip file as a hex string optionally
+	// prefixed with 'sha256:'
+	Checksum string `yaml:"checksum"`
+	URL      string `yaml:"url"`
+}
+
+// in: location - a http(s) url or a local path of the
+//                repository's index or of a directory
+//                containing an 'index.yaml' file
+func NewRepository(location string) *Repository {
+
+	indexLocation := location
+	if ext := strings.ToLower(filepath.Ext(location)); ext != ".yaml" && ext != ".yml" && ext != ".json" {
+		if isHTTPLocation(location) {
+			indexLocation = strings.TrimSuffix(location, "/") + "/" + RepositoryIndexFileName
+		} else {
+			indexLocation = filepath.Join(location, RepositoryIndexFileName)
+		}
+	}
+	return &Repository{
+		indexLocation: indexLocation,
+
+		httpClient: &http.Client{
+			Timeout: 10 * time.Minute,
+		},
+	}
+}
+
+// sets the http client used to read
+// from http(s) repository locations
+func (r *Repository) SetHTTPClient(
+	httpClient *http.Client,
+) {
+	r.httpClient = httpClient
+}
+
+// out: the repository's index
+func (r *Repository) Index() (*RepositoryIndex, error) {
+
+	var (
+		err error
+
+		in   io.ReadCloser
+		data []byte
+	)
+
+	if in, err = r.open(r.indexLocation); err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	if data, err = io.ReadAll(in); err != nil {
+		return nil, err
+	}
+	index := &RepositoryIndex{}
+	if err = yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("invalid cookbook repository index '%s': %s", r.indexLocation, err.Error())
+	}
+	return index, nil
+}
+
+// downloads a cookbook distribution and verifies
+// its checksum
+//
+// in: version - the cookbook distribution to download
+// in: destPath - path of the file to download to
+func (r *Repository) Download(version *RepositoryCookbookVersion, destPath string) error {
+
+	var (
+		err error
+
+		location string
+		in       io.ReadCloser
+		out      *os.File
+	)
+
+	expectedChecksum := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(version.Checksum), "sha256:"))
+	if len(expectedChecksum) == 0 {
+		return fmt.Errorf("cookbook version '%s' does not have a checksum to verify", version.Version)
+	}
+	if location, err = resolveLocation(r.indexLocation, version.URL); err != nil {
+		return err
+	}
+
+	logger.DebugMessage("Downloading cookbook version '%s' from '%s' to '%s'.", version.Version, location, destPath)
+
+	if in, err = r.open(location); err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if out, err = os.Create(destPath); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(out, hash), in); err != nil {
+		out.Close()
+		os.Remove(destPath)
+		return err
+	}
+	if err = out.Close(); err != nil {
+		os.Remove(destPath)
+		return err
+	}
+
+	if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != expectedChecksum {
+		os.Remove(destPath)
+		return fmt.Errorf(
+			"checksum '%s' of cookbook downloaded from '%s' does not match the expected checksum '%s'",
+			checksum, location, expectedChecksum)
+	}
+	return nil
+}
+
+// out: a reader of the content at the given location
+func (r *Repository) open(location string) (io.ReadCloser, error) {
+
+	var (
+		err error
+
+		resp *http.Response
+	)
+
+	if !isHTTPLocation(location) {
+		return os.Open(location)
+	}
+	if resp, err = r.httpClient.Get(location); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("request for '%s' failed with status '%s'", location, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// out: the cookbook with the given name
+func (ri *RepositoryIndex) GetCookbook(name string) *RepositoryCookbook {
+	for _, rc := range ri.Cookbooks {
+		if rc.Name == name {
+			return rc
+		}
+	}
+	return nil
+}
+
+// resolves the latest version of a cookbook that
+// satisfies the given version constraint and that
+// can be imported on the local system
+//
+// in: name - the name of the cookbook
+// in: constraint - a version constraint i.e. "~> 1.2".
+//                  if empty the latest version is resolved.
+// out: the resolved cookbook distribution
+func (ri *RepositoryIndex) Resolve(name, constraint string) (*RepositoryCookbookVersion, error) {
+
+	var (
+		err error
+
+		versionConstraint *semver.VersionConstraint
+		version, latest   *semver.Version
+
+		resolved *RepositoryCookbookVersion
+	)
+
+	rc := ri.GetCookbook(name)
+	if rc == nil {
+		return nil, fmt.Errorf("cookbook '%s' was not found in the repository", name)
+	}
+	if len(strings.TrimSpace(constraint)) > 0 {
+		if versionConstraint, err = semver.ParseVersionConstraint(constraint); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rv := range rc.Versions {
+		if rv.TargetOsName != runtime.GOOS || rv.TargetOsArch != runtime.GOARCH {
+			continue
+		}
+		if version, err = semver.ParseVersion(rv.Version); err != nil {
+			logger.DebugMessage(
+				"Ignoring version '%s' of cookbook '%s' in repository: %s",
+				rv.Version, name, err.Error())
+			continue
+		}
+		if versionConstraint != nil && !versionConstraint.Check(version) {
+			continue
+		}
+		if latest == nil || version.Compare(latest) > 0 {
+			latest = version
+			resolved = rv
+		}
+	}
+	if resolved == nil {
+		if versionConstraint != nil {
+			return nil, fmt.Errorf(
+				"cookbook '%s' does not have a version for %s/%s that satisfies '%s'",
+				name, runtime.GOOS, runtime.GOARCH, constraint)
+		}
+		return nil, fmt.Errorf(
+			"cookbook '%s' does not have a version for %s/%s",
+			name, runtime.GOOS, runtime.GOARCH)
+	}
+	return resolved, nil
+}
+
+// resolves the latest compatible version of a cookbook
+// in the given repository and imports it
+//
+// in: repo - the repository to import from
+// in: name - the name of the cookbook
+// in: constraint - a version constraint i.e. "~> 1.2".
+//                  if empty the latest version is imported.
+// out: the metadata of the imported cookbook
+func (c *Cookbook) ImportFromRepository(repo *Repository, name, constraint string) (*CookbookMetadata, error) {
+
+	var (
+		err error
+
+		index   *RepositoryIndex
+		version *RepositoryCookbookVersion
+
+		downloadPath string
+	)
+
+	if index, err = repo.Index(); err != nil {
+		return nil, err
+	}
+	if version, err = index.Resolve(name, constraint); err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Join(c.workspacePath, "cookbook"), 0755); err != nil {
+		return nil, err
+	}
+	if downloadPath, err = os.MkdirTemp(filepath.Join(c.workspacePath, "cookbook"), ".download-"); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(downloadPath)
+
+	zipPath := filepath.Join(downloadPath, cookbookZipFile)
+	if err = repo.Download(version, zipPath); err != nil {
+		return nil, err
+	}
+	if err = c.ImportCookbook(zipPath); err != nil {
+		return nil, err
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	cm := c.cookbooks[name]
+	if cm == nil || cm.CookbookVersion != version.Version {
+		return nil, fmt.Errorf(
+			"cookbook downloaded as '%s' version '%s' was not imported with the same name and version",
+			name, version.Version)
+	}
+	return cm, nil
+}
+
+// checks the given repository for newer versions of the
+// imported cookbooks. the latest compatible version of
+// each cookbook is set in the cookbook's metadata.
+//
+// in: repo - the repository to check
+// out: the imported cookbooks that have newer versions
+func (c *Cookbook) CheckForUpdates(repo *Repository) ([]*CookbookMetadata, error) {
+
+	var (
+		err error
+
+		index   *RepositoryIndex
+		latest  *RepositoryCookbookVersion
+		current *semver.Version
+		version *semver.Version
+	)
+
+	if index, err = repo.Index(); err != nil {
+		return nil, err
+	}
+
+	updates := []*CookbookMetadata{}
+	for _, cm := range c.CookbookList(true) {
+		if index.GetCookbook(cm.CookbookName) == nil {
+			continue
+		}
+		if latest, err = index.Resolve(cm.CookbookName, ""); err != nil {
+			logger.DebugMessage("No compatible versions of cookbook '%s' found: %s", cm.CookbookName, err.Error())
+			continue
+		}
+		c.mx.Lock()
+		cm.LatestVersion = latest.Version
+		c.mx.Unlock()
+
+		if current, err = semver.ParseVersion(cm.CookbookVersion); err != nil {
+			logger.DebugMessage(
+				"Unable to compare imported cookbook '%s' version '%s' with repository version '%s': %s",
+				cm.CookbookName, cm.CookbookVersion, latest.Version, err.Error())
+			continue
+		}
+		if version, err = semver.ParseVersion(latest.Version); err == nil && version.Compare(current) > 0 {
+			updates = append(updates, cm)
+		}
+	}
+	return updates, nil
+}
+
+// out: true if the location is a http(s) url
+func isHTTPLocation(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// resolves a location relative to the location
+// of the index it was read from
+//
+// in: indexLocation - the location of the index
+// in: location - an absolute or relative location
+// out: the absolute location
+func resolveLocation(indexLocation, location string) (string, error) {
+
+	var (
+		err error
+
+		base, ref *url.URL
+	)
+
+	if isHTTPLocation(location) || filepath.IsAbs(location) {
+		return location, nil
+	}
+	if !isHTTPLocation(indexLocation) {
+		return filepath.Join(filepath.Dir(indexLocation), filepath.FromSlash(location)), nil
+	}
+	if base, err = url.Parse(indexLocation); err != nil {
+		return "", err
+	}
+	if ref, err = url.Parse(location); err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
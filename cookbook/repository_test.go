@@ -0,0 +1,166 @@
+package cookbook_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/gobuffalo/packr/v2"
+
+	"github.com/appbricks/cloud-builder/cookbook"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	test_data "github.com/appbricks/cloud-builder/test/data"
+)
+
+var _ = Describe("Cookbook Repository", func() {
+
+	var (
+		err error
+
+		outputBuffer,
+		errorBuffer strings.Builder
+
+		repoPath string
+		checksum string
+
+		server *httptest.Server
+	)
+
+	writeIndex := func(checksum string) {
+
+		index := fmt.Sprintf(`
+cookbooks:
+- name: minecraft
+  description: Minecraft server
+  versions:
+  - version: 1.2.3
+    target-os-name: %[1]s
+    target-os-arch: %[2]s
+    checksum: sha256:%[3]s
+    url: dist/minecraft-1.2.3.zip
+  - version: 1.2.9
+    target-os-name: plan9
+    target-os-arch: %[2]s
+    checksum: sha256:%[3]s
+    url: dist/minecraft-1.2.9.zip
+  - version: 2.0.0
+    target-os-name: %[1]s
+    target-os-arch: %[2]s
+    checksum: %[3]s
+    url: dist/minecraft-2.0.0.zip
+`,
+			runtime.GOOS, runtime.GOARCH, checksum,
+		)
+		err = os.WriteFile(filepath.Join(repoPath, cookbook.RepositoryIndexFileName), []byte(index), 0644)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		err = test_data.EnsureCookbookIsBuilt(workspacePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		repoPath = filepath.Join(workspacePath, "repository", "index")
+		os.RemoveAll(filepath.Dir(repoPath))
+		err = os.MkdirAll(filepath.Join(repoPath, "dist"), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := os.ReadFile(filepath.Join(workspacePath, "import", "cookbook.zip"))
+		Expect(err).NotTo(HaveOccurred())
+		err = os.WriteFile(filepath.Join(repoPath, "dist", "minecraft-1.2.3.zip"), data, 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		sum := sha256.Sum256(data)
+		checksum = hex.EncodeToString(sum[:])
+		writeIndex(checksum)
+
+		server = httptest.NewServer(http.FileServer(http.Dir(repoPath)))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("resolves the latest compatible version of a cookbook", func() {
+
+		index, err := cookbook.NewRepository(repoPath).Index()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(index.GetCookbook("minecraft").Description).To(Equal("Minecraft server"))
+
+		version, err := index.Resolve("minecraft", "~> 1.2.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version.Version).To(Equal("1.2.3"))
+
+		version, err = index.Resolve("minecraft", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version.Version).To(Equal("2.0.0"))
+
+		_, err = index.Resolve("minecraft", "> 2.0")
+		Expect(err).To(MatchError(fmt.Sprintf(
+			"cookbook 'minecraft' does not have a version for %s/%s that satisfies '> 2.0'",
+			runtime.GOOS, runtime.GOARCH)))
+
+		_, err = index.Resolve("unknown", "")
+		Expect(err).To(MatchError("cookbook 'unknown' was not found in the repository"))
+	})
+
+	It("downloads, verifies and imports a cookbook and checks for updates", func() {
+
+		cookbookDistPath := filepath.Join(workspacePath, "dist")
+		box := packr.New(cookbookDistPath, cookbookDistPath)
+
+		c, err := cookbook.NewCookbook(box, filepath.Join(workspacePath, "repository", "workspace"), &outputBuffer, &errorBuffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		repo := cookbook.NewRepository(server.URL)
+
+		cm, err := c.ImportFromRepository(repo, "minecraft", "~> 1.2.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cm.CookbookName).To(Equal("minecraft"))
+		Expect(cm.CookbookVersion).To(Equal("1.2.3"))
+		Expect(cm.Imported).To(BeTrue())
+
+		validateCoobookRecipes(c, map[string][]string{
+			"test:basic":       {"aws", "google"},
+			"test:simple":      {"google"},
+			"minecraft:server": {"aws", "azure", "docker", "google"},
+		})
+
+		updates, err := c.CheckForUpdates(repo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(updates)).To(Equal(1))
+		Expect(updates[0].CookbookName).To(Equal("minecraft"))
+		Expect(updates[0].LatestVersion).To(Equal("2.0.0"))
+
+		cookbookList := c.CookbookList(true)
+		Expect(len(cookbookList)).To(Equal(1))
+		Expect(cookbookList[0].LatestVersion).To(Equal("2.0.0"))
+
+		err = c.DeleteImportedCookbook("minecraft")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does not import a cookbook whose checksum does not match", func() {
+
+		writeIndex(strings.Repeat("0", len(checksum)))
+
+		cookbookDistPath := filepath.Join(workspacePath, "dist")
+		box := packr.New(cookbookDistPath, cookbookDistPath)
+
+		c, err := cookbook.NewCookbook(box, filepath.Join(workspacePath, "repository", "workspace"), &outputBuffer, &errorBuffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.ImportFromRepository(cookbook.NewRepository(repoPath), "minecraft", "1.2.3")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("does not match the expected checksum"))
+		Expect(len(c.CookbookList(true))).To(Equal(0))
+	})
+})
@@ -0,0 +1,278 @@
+package cookbook
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/appbricks/cloud-builder/terraform"
+	"github.com/mevansam/goutils/logger"
+	"github.com/mevansam/goutils/run"
+	"github.com/mevansam/goutils/utils"
+)
+
+/**
+ * Cookbook Recipe Validation
+ */
+
+// result of validating the terraform
+// configuration of a recipe for an iaas
+type RecipeValidation struct {
+	RecipeKey string
+	IaaS      string
+
+	// diagnostics reported by terraform
+	Result *terraform.ValidationResult
+	// error if validation could not be run
+	Err error
+}
+
+// out: true if terraform validated the recipe's configuration
+func (rv *RecipeValidation) Valid() bool {
+	return rv.Err == nil && rv.Result != nil && rv.Result.Valid
+}
+
+// results of validating all recipes of a cookbook
+type ValidationReport struct {
+	Recipes []*RecipeValidation
+}
+
+// out: true if all recipes are valid
+func (vr *ValidationReport) Valid() bool {
+	for _, rv := range vr.Recipes {
+		if !rv.Valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// out: the recipe validations that failed
+func (vr *ValidationReport) Invalid() []*RecipeValidation {
+
+	invalid := []*RecipeValidation{}
+	for _, rv := range vr.Recipes {
+		if !rv.Valid() {
+			invalid = append(invalid, rv)
+		}
+	}
+	return invalid
+}
+
+func (vr *ValidationReport) String() string {
+
+	var (
+		out strings.Builder
+	)
+
+	for _, rv := range vr.Recipes {
+		out.WriteString(fmt.Sprintf("%s (%s): ", rv.RecipeKey, rv.IaaS))
+		if rv.Err != nil {
+			out.WriteString(fmt.Sprintf("Validation failed: %s", rv.Err.Error()))
+		} else {
+			out.WriteString(rv.Result.String())
+		}
+		out.WriteRune('\n')
+	}
+	return out.String()
+}
+
+// Validates the terraform configuration of every recipe
+// for each iaas it supports by running 'terraform validate'
+// in an isolated working directory of the recipe. The
+// working directory is removed once validation completes.
+//
+// out: report with the diagnostics of each recipe
+func (c *Cookbook) ValidateRecipes() *ValidationReport {
+
+	recipes := []*recipe{}
+
+	c.mx.Lock()
+	for _, rr := range c.recipes {
+		for _, r := range rr {
+			recipes = append(recipes, r.(*recipe))
+		}
+	}
+	c.mx.Unlock()
+
+	sort.Slice(recipes, func(i, j int) bool {
+		if recipes[i].RecipeKey() == recipes[j].RecipeKey() {
+			return recipes[i].RecipeIaaS() < recipes[j].RecipeIaaS()
+		}
+		return recipes[i].RecipeKey() < recipes[j].RecipeKey()
+	})
+
+	report := &ValidationReport{
+		Recipes: make([]*RecipeValidation, 0, len(recipes)),
+	}
+	for _, r := range recipes {
+		rv := &RecipeValidation{
+			RecipeKey: r.RecipeKey(),
+			IaaS:      r.RecipeIaaS(),
+		}
+		if rv.Err = r.validate(); rv.Err == nil {
+			rv.Result, rv.Err = r.validateConfig()
+		}
+		if rv.Err != nil {
+			logger.DebugMessage(
+				"Cookbook.ValidateRecipes(): Validation of recipe '%s' for iaas '%s' failed: %s",
+				rv.RecipeKey, rv.IaaS, rv.Err.Error())
+		}
+		report.Recipes = append(report.Recipes, rv)
+	}
+	return report
+}
+
+// runs 'terraform validate' for the recipe in a temporary
+// working directory. 'terraform init' writes the dependency
+// lock file and module references to the templates it is
+// run in so it is run in a copy of the recipe's templates
+// in order to never change the cookbook's templates.
+func (r *recipe) validateConfig() (*terraform.ValidationResult, error) {
+
+	var (
+		err error
+
+		validatePath string
+		cli          run.CLI
+		result       *terraform.ValidationResult
+
+		outputBuffer,
+		errorBuffer bytes.Buffer
+	)
+
+	if err = os.MkdirAll(r.workingDirectory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if validatePath, err = os.MkdirTemp(r.workingDirectory, ".validate-"); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(validatePath)
+
+	if cli, err = r.CreateCLI(filepath.Base(validatePath), &outputBuffer, &errorBuffer); err != nil {
+		return nil, err
+	}
+	configPath := filepath.Join(validatePath, "templates")
+	if err = copyTemplates(r.tfConfigPath, configPath); err != nil {
+		return nil, err
+	}
+
+	runner := terraform.NewRunner(cli, configPath, r.tfPluginPath, map[string]terraform.Input{})
+	runner.SetEnv(map[string]string{
+		"TF_DATA_DIR": filepath.Join(validatePath, contextFolder),
+	})
+
+	if result, err = runner.Validate(); err != nil {
+		if errOutput := strings.TrimSpace(errorBuffer.String()); len(errOutput) > 0 {
+			return nil, fmt.Errorf("%s: %s", err.Error(), errOutput)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// copies the files of the given templates directory
+// excluding terraform's data directory
+//
+// in: srcPath - the templates directory to copy
+// in: destPath - the directory to copy the templates to
+func copyTemplates(srcPath, destPath string) error {
+
+	return filepath.WalkDir(srcPath, func(p string, de fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(srcPath, p)
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			if de.Name() == contextFolder {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(destPath, relPath), os.ModePerm)
+		}
+		// linked templates are copied as files
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			return utils.CopyFiles(p, filepath.Join(destPath, relPath), 1024)
+		}
+		return nil
+	})
+}
+
+// annotation lint findings of a recipe's
+// terraform templates for an iaas
+type RecipeLint struct {
+	RecipeKey string
+	IaaS      string
+
+	Findings []*terraform.LintFinding
+	// error if the templates could not be linted
+	Err error
+}
+
+// out: true if the recipe has findings with error severity
+func (rl *RecipeLint) HasErrors() bool {
+	if rl.Err != nil {
+		return true
+	}
+	for _, f := range rl.Findings {
+		if f.Severity == terraform.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Lints the annotations in the terraform templates of
+// the given recipe.
+//
+// out: the recipe's lint findings
+func LintRecipe(r Recipe) *RecipeLint {
+
+	rl := &RecipeLint{
+		RecipeKey: r.RecipeKey(),
+		IaaS:      r.RecipeIaaS(),
+	}
+	rl.Findings, rl.Err = terraform.LintTemplates(r.ConfigPath())
+	return rl
+}
+
+// Lints the annotations in the terraform templates of
+// every recipe for each iaas it supports.
+//
+// out: lint findings of each recipe
+func (c *Cookbook) LintRecipes() []*RecipeLint {
+
+	recipes := []Recipe{}
+
+	c.mx.Lock()
+	for _, rr := range c.recipes {
+		for _, r := range rr {
+			recipes = append(recipes, r)
+		}
+	}
+	c.mx.Unlock()
+
+	sort.Slice(recipes, func(i, j int) bool {
+		if recipes[i].RecipeKey() == recipes[j].RecipeKey() {
+			return recipes[i].RecipeIaaS() < recipes[j].RecipeIaaS()
+		}
+		return recipes[i].RecipeKey() < recipes[j].RecipeKey()
+	})
+
+	lints := make([]*RecipeLint, 0, len(recipes))
+	for _, r := range recipes {
+		lints = append(lints, LintRecipe(r))
+	}
+	return lints
+}
@@ -18,7 +18,7 @@ require (
 	github.com/go-oauth2/oauth2/v4 v4.5.2
 	github.com/gobuffalo/packr/v2 v2.8.3
 	github.com/golang/protobuf v1.5.3 // indirect
-	github.com/hashicorp/hcl/v2 v2.15.0 // indirect
+	github.com/hashicorp/hcl/v2 v2.15.0
 	github.com/hashicorp/terraform-config-inspect v0.0.0-20230614215431-f32df32a01cd
 	github.com/kr/pretty v0.3.0 // indirect
 	github.com/mevansam/gocloud v0.0.2
@@ -29,7 +29,7 @@ require (
 	github.com/otiai10/copy v1.9.0 // indirect
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/viper v1.10.1
-	github.com/zclconf/go-cty v1.12.1 // indirect
+	github.com/zclconf/go-cty v1.12.1
 	golang.org/x/oauth2 v0.19.0
 	google.golang.org/api v0.126.0
 	google.golang.org/appengine v1.6.7 // indirect
@@ -0,0 +1,268 @@
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Semantic Versions and Version Constraints
+ */
+
+// a semantic version of a terraform binary
+// or of a cookbook
+type Version struct {
+	Major, Minor, Patch int
+
+	// pre-release label i.e. "beta1" of "1.6.0-beta1"
+	Prerelease string
+}
+
+var versionMatch = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// in: version - a version string i.e. "1.5.7" or "v1.6.0-beta1"
+// out: the parsed version
+func ParseVersion(version string) (*Version, error) {
+
+	v, _, err := parseVersion(version)
+	return v, err
+}
+
+// out: the parsed version and the number of
+//      version segments given in the string
+func parseVersion(version string) (*Version, int, error) {
+
+	m := versionMatch.FindStringSubmatch(strings.TrimSpace(version))
+	if m == nil {
+		return nil, 0, fmt.Errorf("invalid version '%s'", version)
+	}
+
+	v := &Version{Prerelease: m[4]}
+	segments := 0
+	for i, n := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if len(m[i+1]) > 0 {
+			*n, _ = strconv.Atoi(m[i+1])
+			segments++
+		}
+	}
+	return v, segments, nil
+}
+
+// out: -1, 0 or 1 if this version is less
+//      than, equal to or greater than the
+//      given version
+func (v *Version) Compare(other *Version) int {
+
+	for _, d := range []int{
+		v.Major - other.Major,
+		v.Minor - other.Minor,
+		v.Patch - other.Patch,
+	} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+
+	// a pre-release has a lower
+	// precedence than the release
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(other.Prerelease) == 0:
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// compares pre-release labels by their dot separated
+// identifiers as semver does. numeric identifiers are
+// compared numerically and have a lower precedence than
+// alphanumeric identifiers. a label whose identifiers
+// are a prefix of the other's has a lower precedence.
+//
+// out: -1, 0 or 1 if label a is less than, equal
+//      to or greater than label b
+func comparePrerelease(a, b string) int {
+
+	ids1 := strings.Split(a, ".")
+	ids2 := strings.Split(b, ".")
+
+	for i := 0; i < len(ids1) && i < len(ids2); i++ {
+		if cmp := compareIdentifier(ids1[i], ids2[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	switch {
+	case len(ids1) < len(ids2):
+		return -1
+	case len(ids1) > len(ids2):
+		return 1
+	}
+	return 0
+}
+
+var identifierPartMatch = regexp.MustCompile(`\d+|\D+`)
+
+// compares pre-release identifiers. as terraform labels
+// do not separate the number from the label i.e. "rc10",
+// alphanumeric identifiers are compared by their runs of
+// digits and non-digits so that "rc2" is less than "rc10".
+func compareIdentifier(a, b string) int {
+
+	n1, err1 := strconv.ParseUint(a, 10, 64)
+	n2, err2 := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case err1 == nil && err2 == nil:
+		return compareUint(n1, n2)
+	case err1 == nil:
+		return -1
+	case err2 == nil:
+		return 1
+	}
+
+	parts1 := identifierPartMatch.FindAllString(a, -1)
+	parts2 := identifierPartMatch.FindAllString(b, -1)
+
+	for i := 0; i < len(parts1) && i < len(parts2); i++ {
+		n1, err1 = strconv.ParseUint(parts1[i], 10, 64)
+		n2, err2 = strconv.ParseUint(parts2[i], 10, 64)
+
+		if err1 == nil && err2 == nil {
+			if cmp := compareUint(n1, n2); cmp != 0 {
+				return cmp
+			}
+		} else if parts1[i] != parts2[i] {
+			return strings.Compare(parts1[i], parts2[i])
+		}
+	}
+	switch {
+	case len(parts1) < len(parts2):
+		return -1
+	case len(parts1) > len(parts2):
+		return 1
+	}
+	return 0
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (v *Version) String() string {
+	if len(v.Prerelease) > 0 {
+		return fmt.Sprintf("%d.%d.%d-%s", v.Major, v.Minor, v.Patch, v.Prerelease)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// a terraform style version constraint i.e.
+// ">= 1.3.0, < 1.6.0" or "~> 1.5.0". a version
+// without an operator must match exactly.
+type VersionConstraint struct {
+	constraint string
+
+	conditions []versionCondition
+}
+
+type versionCondition struct {
+	operator string
+	version  *Version
+
+	// number of segments given in the constraint
+	// which determines the range allowed by "~>"
+	segments int
+}
+
+var versionConditionMatch = regexp.MustCompile(`^(=|!=|>=|<=|>|<|~>)?\s*(\S+)$`)
+
+// in: constraint - a comma separated list of version conditions
+// out: the parsed version constraint
+func ParseVersionConstraint(constraint string) (*VersionConstraint, error) {
+
+	var (
+		err error
+	)
+
+	vc := &VersionConstraint{
+		constraint: constraint,
+		conditions: []versionCondition{},
+	}
+	for _, c := range strings.Split(constraint, ",") {
+
+		m := versionConditionMatch.FindStringSubmatch(strings.TrimSpace(c))
+		if m == nil {
+			return nil, fmt.Errorf("invalid version constraint '%s'", constraint)
+		}
+		condition := versionCondition{operator: m[1]}
+		if condition.version, condition.segments, err = parseVersion(m[2]); err != nil {
+			return nil, fmt.Errorf("invalid version constraint '%s': %s", constraint, err.Error())
+		}
+		if len(condition.operator) == 0 {
+			condition.operator = "="
+		}
+		vc.conditions = append(vc.conditions, condition)
+	}
+	return vc, nil
+}
+
+// out: true if the given version satisfies
+//      all conditions of the constraint
+func (vc *VersionConstraint) Check(version *Version) bool {
+
+	for _, c := range vc.conditions {
+		if !c.check(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (vc *VersionConstraint) String() string {
+	return vc.constraint
+}
+
+func (c versionCondition) check(version *Version) bool {
+
+	cmp := version.Compare(c.version)
+	switch c.operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "~>":
+		// allows only the right-most given
+		// version segment to be incremented
+		if cmp < 0 {
+			return false
+		}
+		switch c.segments {
+		case 1, 2:
+			return version.Major == c.version.Major
+		default:
+			return version.Major == c.version.Major &&
+				version.Minor == c.version.Minor
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package semver_test
+
+import (
+	"github.com/appbricks/cloud-builder/semver"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Version", func() {
+
+	It("parses and compares versions", func() {
+
+		v, err := semver.ParseVersion("v1.5.7")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.String()).To(Equal("1.5.7"))
+
+		beta, err := semver.ParseVersion("1.6.0-beta1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(beta.String()).To(Equal("1.6.0-beta1"))
+
+		release, err := semver.ParseVersion("1.6")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(release.String()).To(Equal("1.6.0"))
+
+		Expect(v.Compare(beta)).To(Equal(-1))
+		Expect(beta.Compare(release)).To(Equal(-1))
+		Expect(release.Compare(beta)).To(Equal(1))
+		Expect(release.Compare(release)).To(Equal(0))
+
+		_, err = semver.ParseVersion("latest")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("compares pre-release labels by their identifiers", func() {
+
+		versions := []string{
+			"1.6.0-alpha",
+			"1.6.0-alpha.1",
+			"1.6.0-alpha.beta",
+			"1.6.0-beta.2",
+			"1.6.0-beta.11",
+			"1.6.0-beta1",
+			"1.6.0-beta2",
+			"1.6.0-rc2",
+			"1.6.0-rc10",
+			"1.6.0",
+		}
+		for i := 0; i < len(versions)-1; i++ {
+			lower, err := semver.ParseVersion(versions[i])
+			Expect(err).NotTo(HaveOccurred())
+			higher, err := semver.ParseVersion(versions[i+1])
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lower.Compare(higher)).To(Equal(-1), "%s < %s", versions[i], versions[i+1])
+			Expect(higher.Compare(lower)).To(Equal(1), "%s > %s", versions[i+1], versions[i])
+		}
+	})
+
+	It("checks versions against version constraints", func() {
+
+		checks := []struct {
+			constraint string
+			version    string
+			satisfied  bool
+		}{
+			{"1.5.7", "1.5.7", true},
+			{"1.5.7", "1.5.6", false},
+			{"= 1.5.7", "1.5.7", true},
+			{"!= 1.5.7", "1.5.7", false},
+			{">= 1.3.0, < 1.6.0", "1.5.7", true},
+			{">= 1.3.0, < 1.6.0", "1.6.0", false},
+			{"> 1.3", "1.3.1", true},
+			{"<= 1.3", "1.3.1", false},
+			{"~> 1.5.0", "1.5.7", true},
+			{"~> 1.5.0", "1.6.0", false},
+			{"~> 1.5", "1.9.2", true},
+			{"~> 1.5", "2.0.0", false},
+			{"~> 1.5", "1.4.9", false},
+		}
+		for _, c := range checks {
+			constraint, err := semver.ParseVersionConstraint(c.constraint)
+			Expect(err).NotTo(HaveOccurred())
+			version, err := semver.ParseVersion(c.version)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(constraint.Check(version)).To(Equal(c.satisfied),
+				"version '%s' with constraint '%s'", c.version, c.constraint)
+		}
+
+		_, err := semver.ParseVersionConstraint(">= 1.3.0, <")
+		Expect(err).To(HaveOccurred())
+	})
+})
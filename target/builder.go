@@ -1,6 +1,7 @@
 package target
 
 import (
+	pcontext "context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,9 +28,39 @@ type Builder struct {
 	cli          run.CLI
 	configInputs map[string]terraform.Input
 
+	// buffers the output of operations that
+	// can be interrupted is written to
+	outputBuffer,
+	errorBuffer io.Writer
+
+	// captures the cli's error output
+	// to detect state lock errors
+	errorCapture *terraform.ErrorCapture
+
+	// receives progress events of
+	// the builder's operations
+	eventHandler terraform.EventHandler
+
+	// resource addresses to limit plan,
+	// launch and delete operations to
+	targets []string
+	// resource instances to replace when the
+	// target is next launched. the set is saved
+	// to the working directory so it is kept
+	// until a launch replaces the resources.
+	rebuild []string
+
+	// only launch plans that have been
+	// reviewed and approved
+	requireApprovedPlan bool
+
 	output map[string]terraform.Output
 }
 
+// file in the working directory with the
+// resources to replace on the next launch
+const rebuildFileName = `tf.rebuild.json`
+
 // in: cookbookRecipe - the recipe to create a launcher for
 // in: cloudProvider - the cloud provider for the cloud to launch the recipe in
 // in: cloudBackend - the backend where launch state will be saved
@@ -49,8 +80,15 @@ func NewBuilder(
 		err error
 
 		cli run.CLI
+
+		errorCapture *terraform.ErrorCapture
 	)
 
+	if errorBuffer != nil {
+		errorCapture = terraform.NewErrorCapture(errorBuffer)
+		errorBuffer = errorCapture
+	}
+
 	recipe := cookbookRecipe.(cookbook.Recipe)
 	if cli, err = recipe.CreateCLI(
 		pathKey,
@@ -67,6 +105,11 @@ func NewBuilder(
 
 		cli:          cli,
 		configInputs: make(map[string]terraform.Input),
+
+		outputBuffer: outputBuffer,
+		errorBuffer:  errorBuffer,
+
+		errorCapture: errorCapture,
 	}
 	if cloudProvider != nil {
 		builder.provider = cloudProvider.(provider.CloudProvider)
@@ -77,9 +120,14 @@ func NewBuilder(
 
 	for _, variable := range recipe.GetVariables() {
 		builder.configInputs[variable.Name] = terraform.Input{
-			Optional: variable.Optional,
+			Optional:    variable.Optional,
+			Type:        variable.Type,
+			Validations: variable.Validations,
 		}
 	}
+	if err = builder.readRebuild(); err != nil {
+		return nil, err
+	}
 
 	return builder, nil
 }
@@ -92,10 +140,28 @@ func (b *Builder) newRunner() (*terraform.Runner, error) {
 		b.recipe.PluginPath(),
 		b.configInputs,
 	)
+	runner.SetEventHandler(b.eventHandler)
+	runner.SetTargets(b.targets)
+	runner.SetReplace(b.rebuild)
+	runner.SetErrorCapture(b.errorCapture)
+	if b.outputBuffer != nil {
+		// operations run with a cancellable context are run
+		// as processes that are interrupted when cancelled
+		runner.SetOutput(b.outputBuffer, b.errorBuffer)
+	}
+	runner.SetRequireApprovedPlan(b.requireApprovedPlan)
+
 	err := b.setEnvVars(runner)
 	return runner, err
 }
 
+// streams typed progress events from the builder's plan, launch
+// and delete operations to the given handler instead of writing
+// the raw terraform output to the builder's output buffer
+func (b *Builder) SetEventHandler(handler terraform.EventHandler) {
+	b.eventHandler = handler
+}
+
 func (b *Builder) setEnvVars(runner *terraform.Runner) error {
 	
 	var (
@@ -123,7 +189,7 @@ func (b *Builder) setEnvVars(runner *terraform.Runner) error {
 }
 
 // prepare and return template variables
-func (b *Builder) getTemplateVars(asTfEnv bool) (map[string]string, error) {
+func (b *Builder) getTemplateVars() (map[string]string, error) {
 
 	var (
 		err error
@@ -161,16 +227,38 @@ func (b *Builder) getTemplateVars(asTfEnv bool) (map[string]string, error) {
 					inputField.Name())
 			}	
 		}
-		if asTfEnv {
-			vars["TF_VAR_"+inputField.Name()] = *value
-		} else {
-			vars[inputField.Name()] = *value
-		}
+		vars[inputField.Name()] = *value
 	}
 
 	return vars, nil
 }
 
+// limits the builder's plan, launch and delete operations
+// to the given resource addresses, which must reference
+// resources in the target's state. an empty list resets
+// the builder to operate on all the target's resources.
+func (b *Builder) SetTargets(addresses []string) error {
+
+	var (
+		err error
+
+		runner *terraform.Runner
+	)
+
+	if runner, err = b.newRunner(); err != nil {
+		return err
+	}
+	if err = runner.ValidateTargets(addresses); err != nil {
+		return err
+	}
+	// ensure a plan created for a different set
+	// of targets is not applied on next launch
+	runner.DiscardPlan()
+
+	b.targets = addresses
+	return nil
+}
+
 // this build's local state
 // - is build's run state present
 // - is build's resource state local
@@ -206,6 +294,12 @@ func (b *Builder) GetLocalBuildState() (bool, bool) {
 
 // initialize the target
 func (b *Builder) Initialize() error {
+	return b.InitializeWithContext(pcontext.Background())
+}
+
+// initialize the target stopping terraform
+// if the given context is cancelled
+func (b *Builder) InitializeWithContext(ctx pcontext.Context) error {
 
 	var (
 		err error
@@ -228,7 +322,7 @@ func (b *Builder) Initialize() error {
 	runner.SetBackend(vars)
 
 	// initialize terraform configuration
-	return runner.Init()
+	return runner.InitWithContext(ctx)
 }
 
 // initialize if not initialized
@@ -250,43 +344,80 @@ func (b *Builder) AutoInitialize() error {
 }
 
 // show launch plan
-func (b *Builder) ShowLaunchPlan() error {
+//
+// out: summary of the changes the next launch will apply
+func (b *Builder) ShowLaunchPlan() (*terraform.PlanSummary, error) {
 
 	var (
 		err error
 
 		runner *terraform.Runner
 		vars   map[string]string
+
+		summary *terraform.PlanSummary
 	)
 
 	if runner, err = b.newRunner(); err == nil {
-		if vars, err = b.getTemplateVars(false); err == nil {
-			err = runner.Plan(vars)
+		if vars, err = b.getTemplateVars(); err == nil {
+			summary, err = runner.Plan(vars)
 		}
 	}
-	return err
+	return summary, err
 }
 
-// taints deployed instance resources so they
-// are rebuilt next time launch is called
-func (b *Builder) SetRebuildInstances() error {
+// when set the target is only launched if the launch
+// plan shown via ShowLaunchPlan has been approved via
+// ApproveLaunchPlan and has not expired
+func (b *Builder) SetRequireApprovedPlan(require bool) {
+	b.requireApprovedPlan = require
+}
+
+// approves the last launch plan shown. the plan
+// can only be approved if the target's inputs
+// have not changed since it was created.
+//
+// in: approvedBy - the name of the approver
+// out: the approved plan's record
+func (b *Builder) ApproveLaunchPlan(approvedBy string) (*terraform.PlanRecord, error) {
 
 	var (
 		err error
 
 		runner *terraform.Runner
+		vars   map[string]string
+
+		record *terraform.PlanRecord
 	)
 
 	if runner, err = b.newRunner(); err == nil {
-		err = runner.Taint(b.recipe.ResourceInstanceList())
+		if vars, err = b.getTemplateVars(); err == nil {
+			record, err = runner.ApprovePlan(vars, approvedBy)
+		}
 	}
-	return err
+	return record, err
+}
+
+// marks deployed instance resources to be replaced
+// when the target is next launched. the replacement
+// is included in the launch plan.
+func (b *Builder) SetRebuildInstances() error {
+	return b.setRebuild(b.recipe.ResourceInstanceList())
 }
 
-// taints deployed instance's attached data
-// resources so they are rebuilt next time
-// launch is called
+// marks deployed instance's attached data resources
+// to be replaced when the target is next launched.
+// the replacement is included in the launch plan.
 func (b *Builder) SetRebuildInstanceData() error {
+	return b.setRebuild(b.recipe.ResourceInstanceDataList())
+}
+
+// out: resource instances that will be replaced
+//      when the target is next launched
+func (b *Builder) RebuildResources() []string {
+	return b.rebuild
+}
+
+func (b *Builder) setRebuild(resources []string) error {
 
 	var (
 		err error
@@ -294,14 +425,141 @@ func (b *Builder) SetRebuildInstanceData() error {
 		runner *terraform.Runner
 	)
 
-	if runner, err = b.newRunner(); err == nil {
-		err = runner.Taint(b.recipe.ResourceInstanceDataList())
+	if runner, err = b.newRunner(); err != nil {
+		return err
 	}
-	return err
+	// ensure a plan created without the resources
+	// to rebuild is not applied on next launch
+	runner.DiscardPlan()
+
+	rebuildSet := make(map[string]bool)
+	for _, resource := range b.rebuild {
+		rebuildSet[resource] = true
+	}
+	for _, resource := range resources {
+		if !rebuildSet[resource] {
+			b.rebuild = append(b.rebuild, resource)
+			rebuildSet[resource] = true
+		}
+	}
+	return b.writeRebuild()
+}
+
+// reads the resources to rebuild saved
+// in the builder's working directory
+func (b *Builder) readRebuild() error {
+
+	var (
+		err  error
+		data []byte
+	)
+
+	if data, err = os.ReadFile(b.rebuildPath()); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err = json.Unmarshal(data, &b.rebuild); err != nil {
+		return fmt.Errorf(
+			"unable to read the resources to rebuild from '%s': %s",
+			b.rebuildPath(), err.Error())
+	}
+	return nil
+}
+
+// saves the resources to rebuild to the builder's
+// working directory. the file is removed when there
+// are no resources to rebuild.
+func (b *Builder) writeRebuild() error {
+
+	var (
+		err  error
+		data []byte
+	)
+
+	if len(b.rebuild) == 0 {
+		if err = os.Remove(b.rebuildPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if data, err = json.Marshal(b.rebuild); err != nil {
+		return err
+	}
+	return os.WriteFile(b.rebuildPath(), data, 0600)
+}
+
+func (b *Builder) rebuildPath() string {
+	return filepath.Join(b.cli.WorkingDirectory(), rebuildFileName)
 }
 
 // launch the target
 func (b *Builder) Launch() error {
+	return b.LaunchWithContext(pcontext.Background())
+}
+
+// launch the target stopping terraform if the
+// given context is cancelled. a cancelled launch
+// returns terraform.ErrCancelled.
+func (b *Builder) LaunchWithContext(ctx pcontext.Context) error {
+
+	var (
+		err error
+
+		runner *terraform.Runner
+		vars   map[string]string
+	)
+
+	if runner, err = b.newRunner(); err == nil {
+		if vars, err = b.getTemplateVars(); err == nil {
+			if b.output, err = runner.ApplyWithContext(ctx, vars); err == nil {
+				// rebuilt resources have been replaced
+				b.rebuild = nil
+				err = b.writeRebuild()
+			}
+		}
+	}
+	return err
+}
+
+// out: all resource instances deployed for the target
+func (b *Builder) StateList() ([]*terraform.StateResource, error) {
+
+	var (
+		err error
+
+		runner *terraform.Runner
+	)
+
+	if runner, err = b.newRunner(); err != nil {
+		return nil, err
+	}
+	return runner.StateList()
+}
+
+// out: the resource instance deployed for the
+//      target with the given address
+func (b *Builder) StateShow(address string) (*terraform.StateResource, error) {
+
+	var (
+		err error
+
+		runner *terraform.Runner
+	)
+
+	if runner, err = b.newRunner(); err != nil {
+		return nil, err
+	}
+	return runner.StateShow(address)
+}
+
+// imports an existing cloud resource into the target's
+// state. the state is backed up before it is changed.
+//
+// in: address - the resource address to import to
+// in: id - the cloud identifier of the resource to import
+func (b *Builder) ImportResource(address, id string) error {
 
 	var (
 		err error
@@ -311,13 +569,101 @@ func (b *Builder) Launch() error {
 	)
 
 	if runner, err = b.newRunner(); err == nil {
-		if vars, err = b.getTemplateVars(false); err == nil {
-			b.output, err = runner.Apply(vars)
+		if vars, err = b.getTemplateVars(); err == nil {
+			err = runner.Import(vars, address, id)
 		}
 	}
 	return err
 }
 
+// moves a resource in the target's state to a new address.
+// this allows deployed resources to be retained when a
+// recipe's resource addresses change between versions.
+// the state is backed up before it is changed.
+//
+// in: source - the current address of the resource
+// in: destination - the new address of the resource
+func (b *Builder) MoveResource(source, destination string) error {
+
+	var (
+		err error
+
+		runner *terraform.Runner
+	)
+
+	if runner, err = b.newRunner(); err != nil {
+		return err
+	}
+	return runner.StateMove(source, destination)
+}
+
+// removes resources from the target's state without
+// destroying them. the state is backed up before it
+// is changed.
+//
+// in: addresses - the addresses of the resources to remove
+func (b *Builder) RemoveResources(addresses []string) error {
+
+	var (
+		err error
+
+		runner *terraform.Runner
+	)
+
+	if runner, err = b.newRunner(); err != nil {
+		return err
+	}
+	return runner.StateRemove(addresses)
+}
+
+// detects resources of the target that have been
+// changed outside of cloud-builder since the target
+// was last launched
+//
+// out: report of the resources that have drifted
+func (b *Builder) DetectDrift() (*terraform.DriftReport, error) {
+	return b.DetectDriftWithContext(pcontext.Background())
+}
+
+func (b *Builder) DetectDriftWithContext(ctx pcontext.Context) (*terraform.DriftReport, error) {
+
+	var (
+		err error
+
+		runner *terraform.Runner
+		vars   map[string]string
+
+		report *terraform.DriftReport
+	)
+
+	if runner, err = b.newRunner(); err == nil {
+		if vars, err = b.getTemplateVars(); err == nil {
+			report, err = runner.DetectDriftWithContext(ctx, vars)
+		}
+	}
+	return report, err
+}
+
+// releases a stale lock on the target's state left by an
+// operation that did not complete. the lock id is given by
+// the terraform.StateLockError returned by the operation
+// that was unable to acquire the lock.
+//
+// in: lockID - the id of the lock to release
+func (b *Builder) ForceUnlock(lockID string) error {
+
+	var (
+		err error
+
+		runner *terraform.Runner
+	)
+
+	if runner, err = b.newRunner(); err != nil {
+		return err
+	}
+	return runner.ForceUnlock(lockID)
+}
+
 // outputs from last launch
 func (b *Builder) Output() *map[string]terraform.Output {
 	return &b.output
@@ -343,6 +689,13 @@ func (b *Builder) Migrate() error {
 
 // delete all resources created for the target
 func (b *Builder) Delete() error {
+	return b.DeleteWithContext(pcontext.Background())
+}
+
+// delete all resources created for the target
+// stopping terraform if the given context is
+// cancelled
+func (b *Builder) DeleteWithContext(ctx pcontext.Context) error {
 
 	var (
 		err error
@@ -353,18 +706,21 @@ func (b *Builder) Delete() error {
 	)
 
 	if runner, err = b.newRunner(); err == nil {
-		if vars, err = b.getTemplateVars(true); err == nil {
-			runner.AddToEnv(vars)
-			if err = runner.Destroy(); err == nil {
+		if vars, err = b.getTemplateVars(); err == nil {
+			if err = runner.DestroyWithContext(ctx, vars); err == nil && len(b.targets) == 0 {
 				
-				// remove state file 
-				// of deleted deployment
+				// remove state file of deleted deployment
+				// unless only targeted resources were deleted
 				os.RemoveAll(
 					filepath.Join(
 						b.cli.WorkingDirectory(),
 						".terraform", "terraform.tfstate",
 					),
 				)
+				// resources of a deleted deployment
+				// no longer need to be rebuilt
+				b.rebuild = nil
+				err = b.writeRebuild()
 			}
 		}
 	}
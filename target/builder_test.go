@@ -1,13 +1,20 @@
 package target_test
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/appbricks/cloud-builder/target"
+	"github.com/appbricks/cloud-builder/terraform"
 	"github.com/mevansam/goforms/forms"
+	"github.com/mevansam/goutils/run"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 
 	cookbook_mocks "github.com/appbricks/cloud-builder/test/mocks"
 	backend_mocks "github.com/mevansam/gocloud/test/mocks"
@@ -34,6 +41,10 @@ var _ = Describe("Builder", func() {
 
 	BeforeEach(func() {
 		cli = utils_mocks.NewFakeCLI(&outputBuffer, &errorBuffer)
+
+		// variables files are written to the cli's working directory
+		err = os.MkdirAll(cli.WorkingDirectory(), 0755)
+		Expect(err).NotTo(HaveOccurred())
 		recipe = cookbook_mocks.NewFakeRecipe(cli)
 
 		provider = provider_mocks.NewFakeCloudProvider()
@@ -128,6 +139,10 @@ var _ = Describe("Builder", func() {
 				_ = inputForm.SetFieldValue("test_input_1", "arg value 1")
 				_ = inputForm.SetFieldValue("test_input_4", "arg value 4")
 
+				// discard resources to rebuild saved by other tests
+				err = os.RemoveAll(filepath.Join(cli.WorkingDirectory(), "tf.rebuild.json"))
+				Expect(err).NotTo(HaveOccurred())
+
 				builder, err = target.NewBuilder(
 					"test/key",
 					recipe,
@@ -176,9 +191,7 @@ var _ = Describe("Builder", func() {
 						"plan",
 						"-input=false",
 						"-out=/goutils/test/cli/workingdirectory/tf.plan",
-						"-var", "test_input_1=arg value 1",
-						"-var", "test_input_2=arg value 2",
-						"-var", "test_input_4=arg value 4",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
 					},
 					[]string{
 						"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
@@ -190,60 +203,145 @@ var _ = Describe("Builder", func() {
 					"",
 					nil,
 				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"show",
+						"-json",
+						"/goutils/test/cli/workingdirectory/tf.plan",
+					},
+					[]string{
+						"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
+						"TF_VAR_test_input_3=arg value 3",
+						"envvar1_input=provider value 1",
+						"envvar2_input=provider value 2",
+					},
+					`{
+						"terraform_version": "1.5.0",
+						"resource_changes": [
+							{
+								"address": "local_file.basic-test",
+								"mode": "managed",
+								"type": "local_file",
+								"name": "basic-test",
+								"provider_name": "registry.terraform.io/hashicorp/local",
+								"change": {
+									"actions": ["create"],
+									"before_sensitive": false,
+									"after_sensitive": {}
+								}
+							}
+						]
+					}`,
+					"",
+					nil,
+				))
 
-				err = builder.ShowLaunchPlan()
+				summary, err := builder.ShowLaunchPlan()
 				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
 				Expect(outputBuffer.String()).To(Equal("Plan: 1 to add, 0 to change, 0 to destroy."))
+				Expect(summary.String()).To(Equal("Plan: 1 to add, 0 to change, 0 to destroy."))
+				Expect(len(summary.ResourceChanges)).To(Equal(1))
+				Expect(summary.ResourceChanges[0].Address).To(Equal("local_file.basic-test"))
+				Expect(summary.ResourceChanges[0].Action).To(Equal(terraform.ActionCreate))
 			})
 
-			It("taints the target's instance resources", func() {
-
-				for _, resource := range []string{"instance1", "instance2", "instance3"} {
-					cli.ExpectFakeRequest(cli.AddFakeResponse(
-						[]string{
-							"taint",
-							resource,
-						},
-						[]string{
-							"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
-							"TF_VAR_test_input_3=arg value 3",
-							"envvar1_input=provider value 1",
-							"envvar2_input=provider value 2",
-						},
-						resource+" tainted:",
-						"",
-						nil,
-					))
-				}
+			It("replaces the target's instance resources on the next launch", func() {
 
 				err = builder.SetRebuildInstances()
 				Expect(err).NotTo(HaveOccurred())
-				Expect(outputBuffer.String()).To(Equal("instance1 tainted:instance2 tainted:instance3 tainted:"))
-			})
+				err = builder.SetRebuildInstanceData()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(builder.RebuildResources()).To(Equal([]string{"instance1", "instance2", "instance3", "data1", "data2"}))
 
-			It("taints the target's instance data resources", func() {
+				// the resources to rebuild are kept for the
+				// builder of the next operation on the target
+				builder, err = target.NewBuilder(
+					"test/key",
+					recipe,
+					provider,
+					backend,
+					map[string]string{
+						"test_input_3": "arg value 3",
+					},
+					nil, nil,
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(builder.RebuildResources()).To(Equal([]string{"instance1", "instance2", "instance3", "data1", "data2"}))
 
-				for _, resource := range []string{"data1", "data2"} {
-					cli.ExpectFakeRequest(cli.AddFakeResponse(
-						[]string{
-							"taint",
-							resource,
-						},
-						[]string{
-							"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
-							"TF_VAR_test_input_3=arg value 3",
-							"envvar1_input=provider value 1",
-							"envvar2_input=provider value 2",
-						},
-						resource+" tainted:",
-						"",
-						nil,
-					))
-				}
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"plan",
+						"-input=false",
+						"-out=/goutils/test/cli/workingdirectory/tf.plan",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+						"-replace=instance1",
+						"-replace=instance2",
+						"-replace=instance3",
+						"-replace=data1",
+						"-replace=data2",
+					},
+					[]string{
+						"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
+						"TF_VAR_test_input_3=arg value 3",
+						"envvar1_input=provider value 1",
+						"envvar2_input=provider value 2",
+					},
+					"Plan: 5 to add, 0 to change, 5 to destroy.",
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"/goutils/test/cli/workingdirectory/tf.plan",
+					},
+					[]string{
+						"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
+						"TF_VAR_test_input_3=arg value 3",
+						"envvar1_input=provider value 1",
+						"envvar2_input=provider value 2",
+					},
+					"Apply complete!",
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"output",
+						"-json",
+					},
+					[]string{
+						"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
+						"TF_VAR_test_input_3=arg value 3",
+						"envvar1_input=provider value 1",
+						"envvar2_input=provider value 2",
+					},
+					`{}`,
+					"",
+					nil,
+				))
 
-				err = builder.SetRebuildInstanceData()
+				err = builder.Launch()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+				Expect(builder.RebuildResources()).To(BeNil())
+
+				builder, err = target.NewBuilder(
+					"test/key",
+					recipe,
+					provider,
+					backend,
+					map[string]string{
+						"test_input_3": "arg value 3",
+					},
+					nil, nil,
+				)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(outputBuffer.String()).To(Equal("data1 tainted:data2 tainted:"))
+				Expect(builder.RebuildResources()).To(BeNil())
 			})
 
 			It("launches a target", func() {
@@ -254,9 +352,7 @@ var _ = Describe("Builder", func() {
 						"plan",
 						"-input=false",
 						"-out=/goutils/test/cli/workingdirectory/tf.plan",
-						"-var", "test_input_1=arg value 1",
-						"-var", "test_input_2=arg value 2",
-						"-var", "test_input_4=arg value 4",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
 					},
 					[]string{
 						"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
@@ -318,6 +414,171 @@ var _ = Describe("Builder", func() {
 				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
 			})
 
+			It("launches a target with an input field hidden by its condition", func() {
+
+				// a hidden field always has a default
+				// value which is passed to terraform
+				recipe.SetHidden("test_input_2", true)
+				defer recipe.SetHidden("test_input_2", false)
+
+				env := []string{
+					"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
+					"TF_VAR_test_input_3=arg value 3",
+					"envvar1_input=provider value 1",
+					"envvar2_input=provider value 2",
+				}
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"plan",
+						"-input=false",
+						"-out=/goutils/test/cli/workingdirectory/tf.plan",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+					},
+					env,
+					"Plan: 1 to add, 0 to change, 0 to destroy.",
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"/goutils/test/cli/workingdirectory/tf.plan",
+					},
+					env,
+					"Apply complete!",
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"output",
+						"-json",
+					},
+					env,
+					`{}`,
+					"",
+					nil,
+				))
+
+				Expect(recipe.IsVariableVisible("test_input_2")).To(BeFalse())
+				Expect(recipe.IsVariableRequired("test_input_2")).To(BeFalse())
+
+				err = builder.Launch()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+			})
+
+			It("deletes only the targeted resources of a target", func() {
+
+				env := []string{
+					"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
+					"TF_VAR_test_input_3=arg value 3",
+					"envvar1_input=provider value 1",
+					"envvar2_input=provider value 2",
+				}
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"state",
+						"list",
+					},
+					env,
+					"instance1\ninstance2\ndata1\n",
+					"",
+					nil,
+				))
+				err = builder.SetTargets([]string{"instance5"})
+				Expect(err).To(HaveOccurred())
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"state",
+						"list",
+					},
+					env,
+					"instance1\ninstance2\ndata1\n",
+					"",
+					nil,
+				))
+				err = builder.SetTargets([]string{"instance2"})
+				Expect(err).NotTo(HaveOccurred())
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"-destroy",
+						"-auto-approve",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+						"-target=instance2",
+					},
+					[]string{
+						"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
+						"TF_VAR_test_input_3=arg value 3",
+						"envvar1_input=provider value 1",
+						"envvar2_input=provider value 2",
+					},
+					"Destroy complete! Resources: 1 destroyed.",
+					"",
+					nil,
+				))
+
+				err = builder.Delete()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+			})
+
+			It("moves a resource in the target's state", func() {
+
+				env := []string{
+					"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
+					"TF_VAR_test_input_3=arg value 3",
+					"envvar1_input=provider value 1",
+					"envvar2_input=provider value 2",
+				}
+				defer os.RemoveAll(filepath.Join(cli.WorkingDirectory(), "state-backups"))
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"state",
+						"pull",
+					},
+					env,
+					`{"version": 4}`,
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"state",
+						"mv",
+						"instance1",
+						"module.node.instance1",
+					},
+					env,
+					"Successfully moved 1 object(s).",
+					"",
+					nil,
+				))
+
+				err = builder.MoveResource("instance1", "module.node.instance1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+			})
+
+			It("does not launch a target when the context has been cancelled", func() {
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				err = builder.LaunchWithContext(ctx)
+				Expect(err).To(Equal(terraform.ErrCancelled))
+				Expect(outputBuffer.String()).To(Equal(""))
+			})
+
 			It("deletes a target", func() {
 
 				cli.ExpectFakeRequest(cli.AddFakeResponse(
@@ -326,13 +587,11 @@ var _ = Describe("Builder", func() {
 						"apply",
 						"-destroy",
 						"-auto-approve",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
 					},
 					[]string{
 						"TF_DATA_DIR=/goutils/test/cli/workingdirectory/.terraform",
-						"TF_VAR_test_input_1=arg value 1",
-						"TF_VAR_test_input_2=arg value 2",
 						"TF_VAR_test_input_3=arg value 3",
-						"TF_VAR_test_input_4=arg value 4",
 						"envvar1_input=provider value 1",
 						"envvar2_input=provider value 2",
 					},
@@ -346,5 +605,69 @@ var _ = Describe("Builder", func() {
 				Expect(outputBuffer.String()).To(HavePrefix("Destroy complete! Resources: 1 destroyed."))
 			})
 		})
+
+		Context("cancellation", func() {
+
+			var (
+				fakeTerraformDir string
+			)
+
+			BeforeEach(func() {
+				fakeTerraformDir, err = os.MkdirTemp("", "fake-terraform")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(fakeTerraformDir)
+			})
+
+			It("interrupts a running launch when its context is cancelled", func() {
+
+				// fake terraform that plans until it is interrupted
+				fakeTerraformPath := filepath.Join(fakeTerraformDir, "terraform")
+				err = os.WriteFile(fakeTerraformPath, []byte(`#!/bin/sh
+trap 'kill $pid; echo "interrupted"; exit 1' INT
+sleep 10 &
+pid=$!
+echo "started"
+wait $pid
+`), 0755)
+				Expect(err).NotTo(HaveOccurred())
+
+				processOutput := gbytes.NewBuffer()
+				processCLI, err := run.NewCLI(fakeTerraformPath, fakeTerraformDir, processOutput, processOutput)
+				Expect(err).NotTo(HaveOccurred())
+
+				processRecipe := cookbook_mocks.NewFakeRecipe(processCLI)
+				processRecipe.SetRecipePath(testRecipePath)
+
+				processBuilder, err := target.NewBuilder(
+					"test/key",
+					processRecipe,
+					nil,
+					nil,
+					map[string]string{},
+					processOutput,
+					processOutput,
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				result := make(chan error, 1)
+				go func() {
+					result <- processBuilder.LaunchWithContext(ctx)
+				}()
+				Eventually(processOutput, "5s").Should(gbytes.Say("started"))
+
+				cancelled := time.Now()
+				cancel()
+
+				Eventually(result, "5s").Should(Receive(Equal(terraform.ErrCancelled)))
+				Expect(processOutput).To(gbytes.Say("interrupted"))
+				Expect(time.Since(cancelled)).To(BeNumerically("<", 5*time.Second))
+			})
+		})
 	})
 })
@@ -4,7 +4,6 @@ import (
 	pcontext "context"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/json"
 	"fmt"
 	"io"
 	"math"
@@ -443,30 +442,30 @@ func (t *Target) loadRemoteRefs() error {
 		logger.TraceMessage("Target deployment output: %# v", t.Output)
 
 		if output, ok = (*t.Output)["cb_node_description"]; ok {
-			if t.description, ok = output.Value.(string); !ok {
-				return fmt.Errorf("node description key value is not a string")
+			if t.description, err = output.AsString(); err != nil {
+				return fmt.Errorf("node description output: %s", err.Error())
 			}
 		}
 		if output, ok = (*t.Output)["cb_node_version"]; ok {
-			if t.version, ok = output.Value.(string); !ok {
-				return fmt.Errorf("node version key value is not a string")
+			if t.version, err = output.AsString(); err != nil {
+				return fmt.Errorf("node version output: %s", err.Error())
 			}
 		}
 		if output, ok = (*t.Output)["cb_root_ca_cert"]; ok {
-			if t.rootCACert, ok = output.Value.(string); !ok {
-				return fmt.Errorf("node root ca certificate key value is not a string")
+			if t.rootCACert, err = output.AsString(); err != nil {
+				return fmt.Errorf("node root ca certificate output: %s", err.Error())
 			}
 		}
 		if output, ok = (*t.Output)["cb_vpn_type"]; ok {
-			if t.vpnType, ok = output.Value.(string); !ok {
-				return fmt.Errorf("node root vpn type value is not a string")
+			if t.vpnType, err = output.AsString(); err != nil {
+				return fmt.Errorf("node vpn type output: %s", err.Error())
 			}
 		}
 
 		if output, ok = (*t.Output)["cb_managed_instances"]; ok {
 
-			if managedInstanceValues, ok = output.Value.([]interface{}); !ok {
-				return fmt.Errorf("managed instance output is not a list")
+			if managedInstanceValues, err = output.AsList(); err != nil {
+				return fmt.Errorf("managed instance output: %s", err.Error())
 			}
 
 			numInstance := len(managedInstanceValues)
@@ -752,21 +751,13 @@ func (t *Target) NewBuilder(
 		if dt.Output != nil {
 			for name, output := range *dt.Output {
 				if name != "cb_managed_instances" {
-					
-					switch v := output.Value.(type) {
-					case bool:
-						buildVars[name] = strconv.FormatBool(v)
-					case int:
-						buildVars[name] = strconv.Itoa(v)
-					case string:
-						buildVars[name] = v
-					default:
-						b, err := json.Marshal(v)
-						if err != nil {
-							return nil, err
-						}
-						buildVars[name] = string(b)
+					value, err := output.ValueAsString()
+					if err != nil {
+						return nil, fmt.Errorf(
+							"dependent target '%s' output '%s': %s",
+							dt.Key(), name, err.Error())
 					}
+					buildVars[name] = value
 				}
 			}
 		}
@@ -782,6 +773,91 @@ func (t *Target) NewBuilder(
 		errorBuffer)
 }
 
+// out: all resource instances deployed for the target
+func (t *Target) StateList(
+	outputBuffer,
+	errorBuffer io.Writer,
+) ([]*terraform.StateResource, error) {
+
+	var (
+		err error
+
+		builder *Builder
+	)
+
+	if builder, err = t.newStateBuilder(outputBuffer, errorBuffer); err != nil {
+		return nil, err
+	}
+	return builder.StateList()
+}
+
+// out: the resource instance deployed for the
+//      target with the given address
+func (t *Target) StateShow(
+	address string,
+	outputBuffer,
+	errorBuffer io.Writer,
+) (*terraform.StateResource, error) {
+
+	var (
+		err error
+
+		builder *Builder
+	)
+
+	if builder, err = t.newStateBuilder(outputBuffer, errorBuffer); err != nil {
+		return nil, err
+	}
+	return builder.StateShow(address)
+}
+
+// detects resources of a deployed target that have
+// been changed outside of cloud-builder since the
+// target was last launched
+//
+// out: report of the resources that have drifted
+func (t *Target) DetectDrift(
+	outputBuffer,
+	errorBuffer io.Writer,
+) (*terraform.DriftReport, error) {
+
+	var (
+		err error
+
+		builder *Builder
+	)
+
+	if builder, err = t.newStateBuilder(outputBuffer, errorBuffer); err != nil {
+		return nil, err
+	}
+	return builder.DetectDrift()
+}
+
+// returns a builder with an initialized run
+// directory from which state can be queried
+func (t *Target) newStateBuilder(
+	outputBuffer,
+	errorBuffer io.Writer,
+) (*Builder, error) {
+
+	var (
+		err error
+
+		builder *Builder
+	)
+
+	if t.Output == nil {
+		return nil, fmt.Errorf("target '%s' has not been deployed", t.Key())
+	}
+	if builder, err = t.NewBuilder(make(map[string]string), outputBuffer, errorBuffer); err != nil {
+		return nil, err
+	}
+	if err = builder.AutoInitialize(); err != nil {
+		return nil, err
+	}
+	return builder, nil
+}
+
 // Target type's SpaceNode implementation
 
 func (t *Target) Key() string {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
@@ -111,6 +112,43 @@ func (ts *TargetSet) GetTargets() []*Target {
 	return targets
 }
 
+// result of a drift check of a target in the set
+type TargetDrift struct {
+	Target *Target
+
+	Report *terraform.DriftReport
+	Err    error
+}
+
+// detects drift of all deployed targets in the set.
+// targets that have not been deployed are skipped
+// and a failed check does not stop the remaining
+// targets from being checked.
+//
+// out: drift results ordered by target key
+func (ts *TargetSet) DetectDrift(
+	outputBuffer,
+	errorBuffer io.Writer,
+) []*TargetDrift {
+
+	results := []*TargetDrift{}
+	for _, t := range ts.GetTargets() {
+		if t.Output == nil {
+			continue
+		}
+
+		result := &TargetDrift{Target: t}
+		if result.Report, result.Err = t.DetectDrift(outputBuffer, errorBuffer); result.Err != nil {
+			logger.ErrorMessage(
+				"Drift check of target '%s' failed: %s",
+				t.Key(), result.Err.Error(),
+			)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
 func (ts *TargetSet) GetTarget(name string) *Target {
 	logger.TraceMessage(
 		"Retrieving target with name '%s' from: %+v",
@@ -0,0 +1,307 @@
+package terraform
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mevansam/gocloud/provider"
+	"github.com/mevansam/goutils/logger"
+)
+
+/**
+ * Accepted Values Providers
+ */
+
+// provides the values accepted by a recipe input field. a
+// provider is referenced in a variable's @accepted_values
+// annotation as '$<name>' or '$<name>:<arg>'.
+//
+// in: iaas - the iaas of the recipe
+// in: configPath - path of the recipe's terraform templates
+// in: arg - the argument given with the provider reference
+// out: the accepted values. an empty list allows any value.
+type AcceptedValuesProvider func(iaas, configPath, arg string) ([]string, error)
+
+// names of the built-in providers of values of an iaas
+// that are resolved from the iaas' cloud provider
+const (
+	IaaSZones         = "iaas_zones"
+	IaaSInstanceTypes = "iaas_instance_types"
+	IaaSImages        = "iaas_images"
+)
+
+// resolves values of an iaas such as the zones, instance
+// types or images available in a region of the iaas.
+//
+// in: cloudProvider - the cloud provider of the iaas
+// in: region - the region given with the provider reference
+//              i.e. '$iaas_zones:us-east-1' or the cloud
+//              provider's region if one was not given
+// out: the values of the iaas in the region
+type IaaSValuesResolver func(cloudProvider provider.CloudProvider, region string) ([]string, error)
+
+var (
+	acceptedValuesProviders = map[string]AcceptedValuesProvider{
+		"iaas_regions":    iaasRegions,
+		IaaSZones:         iaasValues(IaaSZones),
+		IaaSInstanceTypes: iaasValues(IaaSInstanceTypes),
+		IaaSImages:        iaasValues(IaaSImages),
+		"file":            staticFile,
+	}
+	acceptedValuesCache = make(map[string][]string)
+
+	// resolvers keyed by "<provider name>|<iaas>"
+	iaasValuesResolvers = make(map[string]IaaSValuesResolver)
+	// resolved values keyed by "<provider name>|<iaas>|<region>"
+	iaasValuesCache = make(map[string][]string)
+
+	acceptedValuesMx sync.Mutex
+)
+
+// registers a provider of accepted values that recipes
+// can reference by name. a provider registered with the
+// name of an existing provider replaces it.
+func RegisterAcceptedValuesProvider(name string, valuesProvider AcceptedValuesProvider) {
+
+	acceptedValuesMx.Lock()
+	defer acceptedValuesMx.Unlock()
+
+	acceptedValuesProviders[name] = valuesProvider
+	// discard values of the provider being replaced
+	for key := range acceptedValuesCache {
+		if strings.HasPrefix(key, name+"|") {
+			delete(acceptedValuesCache, key)
+		}
+	}
+}
+
+// registers the resolver of the values of the given iaas
+// provided by the built-in provider with the given name
+// i.e. IaaSZones. a resolver registered for the same
+// provider and iaas as an existing resolver replaces it.
+func RegisterIaaSValuesResolver(name, iaas string, resolver IaaSValuesResolver) {
+
+	acceptedValuesMx.Lock()
+	defer acceptedValuesMx.Unlock()
+
+	iaasValuesResolvers[name+"|"+iaas] = resolver
+	// discard values resolved by the resolver being
+	// replaced or accepting any value as the values
+	// could not be resolved
+	for key := range iaasValuesCache {
+		if strings.HasPrefix(key, name+"|"+iaas+"|") {
+			delete(iaasValuesCache, key)
+		}
+	}
+	for key := range acceptedValuesCache {
+		if strings.HasPrefix(key, name+"|"+iaas+"|") {
+			delete(acceptedValuesCache, key)
+		}
+	}
+}
+
+// discards all cached accepted values so
+// they are retrieved again when next needed
+func ResetAcceptedValuesCache() {
+
+	acceptedValuesMx.Lock()
+	defer acceptedValuesMx.Unlock()
+
+	acceptedValuesCache = make(map[string][]string)
+	iaasValuesCache = make(map[string][]string)
+}
+
+// out: true if the accepted value is
+//      a reference to a values provider
+func isAcceptedValuesReference(value string) bool {
+	return strings.HasPrefix(value, "$")
+}
+
+// out: the name and argument of an accepted values
+//      provider reference i.e. "$file:sizes.txt"
+func parseAcceptedValuesReference(reference string) (string, string) {
+
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(reference), "$"), ":")
+	return name, strings.TrimSpace(arg)
+}
+
+// out: true if a provider with the given name is registered
+func hasAcceptedValuesProvider(name string) bool {
+
+	acceptedValuesMx.Lock()
+	defer acceptedValuesMx.Unlock()
+
+	_, exists := acceptedValuesProviders[name]
+	return exists
+}
+
+// resolves a reference to an accepted values provider.
+// values are cached per provider, iaas and argument.
+//
+// out: the accepted values returned by the provider
+func resolveAcceptedValues(reference, iaas, configPath string) ([]string, error) {
+
+	var (
+		err    error
+		values []string
+	)
+
+	name, arg := parseAcceptedValuesReference(reference)
+	key := strings.Join([]string{name, iaas, configPath, arg}, "|")
+
+	acceptedValuesMx.Lock()
+	valuesProvider, exists := acceptedValuesProviders[name]
+	cachedValues, cached := acceptedValuesCache[key]
+	acceptedValuesMx.Unlock()
+
+	if !exists {
+		return nil, fmt.Errorf("accepted values provider '%s' is not known", reference)
+	}
+	if cached {
+		return cachedValues, nil
+	}
+	if values, err = valuesProvider(iaas, configPath, arg); err != nil {
+		return nil, fmt.Errorf("accepted values provider '%s' failed: %s", reference, err.Error())
+	}
+
+	acceptedValuesMx.Lock()
+	acceptedValuesCache[key] = values
+	acceptedValuesMx.Unlock()
+
+	return values, nil
+}
+
+// built-in provider of the iaas' regions
+func iaasRegions(iaas, configPath, arg string) ([]string, error) {
+
+	var (
+		err error
+
+		cloudProvider provider.CloudProvider
+	)
+
+	if cloudProvider, err = provider.NewCloudProvider(iaas); err != nil {
+		return nil, err
+	}
+	values := []string{}
+	for _, r := range cloudProvider.GetRegions() {
+		values = append(values, r.Name)
+	}
+	return values, nil
+}
+
+// out: a built-in provider of values of an iaas that are
+//      resolved by the resolver registered for the iaas.
+//      values are cached per iaas and region. if values
+//      cannot be resolved any value is accepted.
+func iaasValues(name string) AcceptedValuesProvider {
+
+	return func(iaas, configPath, arg string) ([]string, error) {
+
+		var (
+			err    error
+			values []string
+
+			cloudProvider provider.CloudProvider
+		)
+
+		acceptedValuesMx.Lock()
+		resolver, exists := iaasValuesResolvers[name+"|"+iaas]
+		acceptedValuesMx.Unlock()
+
+		if !exists {
+			logger.WarnMessage(
+				"A resolver of '$%s' values for iaas '%s' has not been registered. Any value will be accepted.",
+				name, iaas)
+			return []string{}, nil
+		}
+		if cloudProvider, err = provider.NewCloudProvider(iaas); err != nil {
+			logger.WarnMessage(
+				"Unable to resolve '$%s' values for iaas '%s': %s. Any value will be accepted.",
+				name, iaas, err.Error())
+			return []string{}, nil
+		}
+		region := arg
+		if len(region) == 0 && cloudProvider.Region() != nil {
+			region = *cloudProvider.Region()
+		}
+
+		key := strings.Join([]string{name, iaas, region}, "|")
+		acceptedValuesMx.Lock()
+		cachedValues, cached := iaasValuesCache[key]
+		acceptedValuesMx.Unlock()
+
+		if cached {
+			return cachedValues, nil
+		}
+		if values, err = resolver(cloudProvider, region); err != nil {
+			logger.WarnMessage(
+				"Unable to resolve '$%s' values for region '%s' of iaas '%s': %s. Any value will be accepted.",
+				name, region, iaas, err.Error())
+			return []string{}, nil
+		}
+		if len(values) == 0 {
+			logger.WarnMessage(
+				"No '$%s' values were resolved for region '%s' of iaas '%s'. Any value will be accepted.",
+				name, region, iaas)
+			return []string{}, nil
+		}
+
+		acceptedValuesMx.Lock()
+		iaasValuesCache[key] = values
+		acceptedValuesMx.Unlock()
+
+		return values, nil
+	}
+}
+
+// built-in provider of values read from a file
+// at a path relative to the recipe's templates
+func staticFile(iaas, configPath, arg string) ([]string, error) {
+
+	if len(arg) == 0 {
+		return nil, fmt.Errorf("the path of the values file was not provided")
+	}
+	path := arg
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(configPath, path)
+	}
+	return readValuesFile(path)
+}
+
+// out: the values in the given file which has a value
+//      per line. blank lines and lines starting with '#'
+//      are ignored.
+func readValuesFile(path string) ([]string, error) {
+
+	var (
+		err  error
+		file *os.File
+	)
+
+	if file, err = os.Open(path); err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := []string{}
+	seen := make(map[string]bool)
+
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		value := strings.TrimSpace(s.Text())
+		if len(value) == 0 || strings.HasPrefix(value, "#") || seen[value] {
+			continue
+		}
+		values = append(values, value)
+		seen[value] = true
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
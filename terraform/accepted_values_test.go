@@ -0,0 +1,132 @@
+package terraform_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/appbricks/cloud-builder/terraform"
+	"github.com/mevansam/gocloud/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Accepted Values Providers", func() {
+
+	var (
+		err error
+
+		valuesTemplatePath string
+
+		calls,
+		zoneCalls int
+	)
+
+	BeforeEach(func() {
+		valuesTemplatePath, err = filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/values", sourceDirPath))
+		Expect(err).NotTo(HaveOccurred())
+
+		calls = 0
+		zoneCalls = 0
+		terraform.ResetAcceptedValuesCache()
+		terraform.RegisterAcceptedValuesProvider("custom_versions",
+			func(iaas, configPath, arg string) ([]string, error) {
+				calls++
+				return []string{iaas + "-1.0", iaas + "-2.0"}, nil
+			},
+		)
+		terraform.RegisterIaaSValuesResolver(terraform.IaaSZones, "aws",
+			func(cloudProvider provider.CloudProvider, region string) ([]string, error) {
+				zoneCalls++
+				if region != "us-east-1" {
+					return nil, fmt.Errorf("region '%s' is not available", region)
+				}
+				return []string{region + "a", region + "b"}, nil
+			},
+		)
+		terraform.RegisterIaaSValuesResolver(terraform.IaaSImages, "aws",
+			func(cloudProvider provider.CloudProvider, region string) ([]string, error) {
+				Expect(cloudProvider.Name()).To(Equal("aws"))
+				return []string{"ami-0a1b2c3d", "ami-4e5f6a7b"}, nil
+			},
+		)
+	})
+
+	It("populates accepted values of fields from providers", func() {
+
+		reader := terraform.NewConfigReader()
+		err = reader.ReadMetadata("values", "aws", valuesTemplatePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		acceptedValues := make(map[string][]string)
+		for _, f := range reader.InputForm().InputFields() {
+			acceptedValues[f.Name()] = f.AcceptedValues()
+		}
+		Expect(acceptedValues["zone"]).To(Equal([]string{"us-east-1a", "us-east-1b"}))
+		Expect(acceptedValues["size"]).To(Equal([]string{"small", "medium", "large"}))
+		Expect(acceptedValues["image"]).To(Equal([]string{"ami-0a1b2c3d", "ami-4e5f6a7b"}))
+		Expect(acceptedValues["version"]).To(Equal([]string{"aws-1.0", "aws-2.0"}))
+	})
+
+	It("accepts any value when the values of an iaas cannot be resolved", func() {
+
+		templatePath, err := os.MkdirTemp("", "values")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(templatePath)
+
+		err = os.WriteFile(filepath.Join(templatePath, "main.tf"), []byte(`
+# @accepted_values: $iaas_instance_types
+variable "instance_type" {
+  type = string
+}
+
+# @accepted_values: $iaas_zones:eu-west-1
+variable "zone" {
+  type = string
+}
+`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		// instance types do not have a resolver and
+		// the zones of the region are not available
+		reader := terraform.NewConfigReader()
+		err = reader.ReadMetadata("values", "aws", templatePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, f := range reader.InputForm().InputFields() {
+			Expect(f.AcceptedValues()).To(BeEmpty())
+		}
+		Expect(zoneCalls).To(Equal(1))
+	})
+
+	It("caches provider values per iaas", func() {
+
+		for _, iaas := range []string{"aws", "aws", "google"} {
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("values", iaas, valuesTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(calls).To(Equal(2))
+		Expect(zoneCalls).To(Equal(1))
+
+		// zones of a region are resolved once for all recipes
+		templatePath, err := os.MkdirTemp("", "values")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(templatePath)
+
+		err = os.WriteFile(filepath.Join(templatePath, "main.tf"), []byte(`
+# @accepted_values: $iaas_zones:us-east-1
+variable "zone" {
+  type = string
+}
+`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		reader := terraform.NewConfigReader()
+		err = reader.ReadMetadata("values", "aws", templatePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reader.InputForm().InputFields()[0].AcceptedValues()).To(Equal([]string{"us-east-1a", "us-east-1b"}))
+		Expect(zoneCalls).To(Equal(1))
+	})
+})
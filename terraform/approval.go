@@ -0,0 +1,253 @@
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mevansam/goutils/logger"
+)
+
+/**
+ * Terraform Plan Records and Approvals
+ */
+
+// record of a plan saved in the working directory
+// which is used to ensure only a plan that was
+// reviewed is applied
+type PlanRecord struct {
+	// hash of the variables, targets and
+	// replacements the plan was created with
+	InputsHash string `json:"inputsHash"`
+	// hash of the saved plan file
+	PlanHash string `json:"planHash,omitempty"`
+
+	CreatedAt time.Time    `json:"createdAt"`
+	Summary   *PlanSummary `json:"summary"`
+
+	Approved   bool       `json:"approved"`
+	ApprovedBy string     `json:"approvedBy,omitempty"`
+	ApprovedAt *time.Time `json:"approvedAt,omitempty"`
+}
+
+// errors returned when a saved plan
+// cannot be approved or applied
+var (
+	ErrNoPlan            = errors.New("no plan has been created")
+	ErrPlanNotApproved   = errors.New("the plan has not been approved")
+	ErrPlanExpired       = errors.New("the plan has expired")
+	ErrPlanInputsChanged = errors.New("the inputs have changed since the plan was created")
+	ErrPlanChanged       = errors.New("the plan has changed since it was recorded")
+)
+
+// plan record file saved alongside the plan
+const tfPlanRecordFileName = `tf.plan.json`
+
+const defaultPlanExpiry = 24 * time.Hour
+
+// out: time at which the plan expires
+func (p *PlanRecord) ExpiresAt(expiry time.Duration) time.Time {
+	return p.CreatedAt.Add(expiry)
+}
+
+// approves the plan in the working directory so it
+// can be applied when the runner requires approved
+// plans. the plan must have been created with the
+// given arguments and must not have expired.
+//
+// in: args - the configuration's variables
+// in: approvedBy - the name of the approver
+// out: the approved plan's record
+func (r *Runner) ApprovePlan(
+	args map[string]string,
+	approvedBy string,
+) (*PlanRecord, error) {
+
+	var (
+		err    error
+		record *PlanRecord
+	)
+
+	if record, err = r.checkPlanRecord(args); err != nil {
+		return nil, err
+	}
+	record.Approved = true
+	record.ApprovedBy = approvedBy
+	approvedAt := time.Now()
+	record.ApprovedAt = &approvedAt
+
+	if err = r.writePlanRecord(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// out: the record of the plan in the working
+//      directory or ErrNoPlan if there is none
+func (r *Runner) GetPlanRecord() (*PlanRecord, error) {
+
+	var (
+		err  error
+		data []byte
+	)
+
+	if data, err = os.ReadFile(r.planRecordPath()); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoPlan
+		}
+		return nil, err
+	}
+	record := &PlanRecord{}
+	if err = json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("invalid plan record: %s", err.Error())
+	}
+	return record, nil
+}
+
+// out: the record of the plan in the working directory
+//      if the plan was created with the given arguments,
+//      has not expired and has not been changed
+func (r *Runner) checkPlanRecord(args map[string]string) (*PlanRecord, error) {
+
+	var (
+		err error
+
+		record *PlanRecord
+		hash   string
+	)
+
+	if _, err = os.Stat(r.planPath()); os.IsNotExist(err) {
+		return nil, ErrNoPlan
+	}
+	if record, err = r.GetPlanRecord(); err != nil {
+		return nil, err
+	}
+	if hash, err = r.inputsHash(args); err != nil {
+		return nil, err
+	}
+	if hash != record.InputsHash {
+		return nil, ErrPlanInputsChanged
+	}
+	if r.planExpiry > 0 && time.Now().After(record.ExpiresAt(r.planExpiry)) {
+		return nil, ErrPlanExpired
+	}
+	if len(record.PlanHash) > 0 {
+		if hash, err = fileHash(r.planPath()); err != nil {
+			return nil, err
+		}
+		if hash != record.PlanHash {
+			return nil, ErrPlanChanged
+		}
+	}
+	return record, nil
+}
+
+// records the plan in the working directory
+// that was created with the given arguments
+func (r *Runner) recordPlan(
+	args map[string]string,
+	summary *PlanSummary,
+) error {
+
+	var (
+		err error
+	)
+
+	record := &PlanRecord{
+		CreatedAt: time.Now(),
+		Summary:   summary,
+	}
+	if record.InputsHash, err = r.inputsHash(args); err != nil {
+		return err
+	}
+	if record.PlanHash, err = fileHash(r.planPath()); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		logger.DebugMessage("Plan file to record was not found: %s", err.Error())
+	}
+	return r.writePlanRecord(record)
+}
+
+func (r *Runner) writePlanRecord(record *PlanRecord) error {
+
+	var (
+		err  error
+		data []byte
+	)
+
+	if data, err = json.MarshalIndent(record, "", "  "); err != nil {
+		return err
+	}
+	return os.WriteFile(r.planRecordPath(), data, 0600)
+}
+
+// out: hash of the variables, targets and replacements
+//      that determine the changes a plan will make
+func (r *Runner) inputsHash(args map[string]string) (string, error) {
+
+	var (
+		err    error
+		tfVars []byte
+	)
+
+	// the variables are hashed in the form they are
+	// passed to terraform where keys are sorted
+	if tfVars, err = r.TFVarsJSON(args); err != nil {
+		return "", err
+	}
+	// variables may also be passed to terraform
+	// via TF_VAR_* variables in the environment
+	tfEnvVars := []string{}
+	for _, e := range r.env {
+		if strings.HasPrefix(e, "TF_VAR_") {
+			tfEnvVars = append(tfEnvVars, e)
+		}
+	}
+	sort.Strings(tfEnvVars)
+
+	h := sha256.New()
+	h.Write(tfVars)
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(tfEnvVars, "\n")))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(r.targets, "\n")))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(r.replace, "\n")))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fileHash(path string) (string, error) {
+
+	var (
+		err  error
+		file *os.File
+	)
+
+	if file, err = os.Open(path); err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (r *Runner) planPath() string {
+	return filepath.Join(r.cli.WorkingDirectory(), tfPlanFileName)
+}
+
+func (r *Runner) planRecordPath() string {
+	return filepath.Join(r.cli.WorkingDirectory(), tfPlanRecordFileName)
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/hashicorp/terraform-config-inspect/tfconfig"
 
 	"github.com/mevansam/gocloud/backend"
-	"github.com/mevansam/gocloud/provider"
 	"github.com/mevansam/goforms/forms"
 	"github.com/mevansam/goutils/logger"
 
@@ -56,10 +55,45 @@ type configReader struct {
 	// key fields
 	keyFields []string
 
+	// terraform type constraints of the
+	// variables keyed by variable name
+	variableTypes map[string]string
+
+	// rules of the variables' validation
+	// blocks keyed by variable name
+	variableValidations map[string][]*VariableValidation
+
+	// conditions that determine if a variable
+	// is shown or required keyed by variable name
+	fieldConditions map[string]*FieldConditions
+
 	// content of terraform templates which
 	// contain variable declarations
 	templatesWithVars map[string][]string
 
+	// the recipe's optional manifest and the
+	// recipe annotations declared in templates
+	// which are merged with the manifest
+	manifest          *RecipeManifest
+	recipeAnnotations map[string]string
+
+	// group annotations declared in templates
+	// keyed by group name and annotation name
+	groupAnnotations map[string]map[string]string
+
+	// groups of the variables in form order
+	variableGroups []*VariableGroup
+
+	// json schema of the input form
+	inputSchema *JSONSchema
+
+	// new names of renamed variables keyed by
+	// the names they were renamed from
+	variableRenames map[string]string
+	// deprecation messages of deprecated
+	// variables keyed by variable name
+	deprecatedVariables map[string]string
+
 	// annotation search regex
 	variableMetadataMatch *regexp.Regexp
 }
@@ -100,11 +134,26 @@ type variableMetadata struct {
 	sensitive bool
 	// @target_key
 	key bool
+	// @visible_if
+	visibleIf string
+	// @required_if
+	requiredIf string
+	// @group
+	group string
+	// @renamed_from
+	renamedFrom []string
+	// @deprecated
+	deprecated    bool
+	deprecatedMsg string
 
 	// metadata for ordering fields
 
 	// @order + fileName + lineNumber
 	order int
+
+	// annotations declared in the comment block
+	// of the variable keyed by annotation name
+	annotations map[string]string
 	// used to infer order when
 	// @order is not provided
 	fileName   string
@@ -114,8 +163,16 @@ type variableMetadata struct {
 func NewConfigReader() *configReader {
 	return &configReader{
 		templatesWithVars: make(map[string][]string),
+		recipeAnnotations: make(map[string]string),
+		groupAnnotations:  make(map[string]map[string]string),
 
-		keyFields: []string{},
+		variableRenames:     make(map[string]string),
+		deprecatedVariables: make(map[string]string),
+
+		keyFields:           []string{},
+		variableTypes:       make(map[string]string),
+		variableValidations: make(map[string][]*VariableValidation),
+		fieldConditions:     make(map[string]*FieldConditions),
 
 		variableMetadataMatch: regexp.MustCompile(`^#\s*\@([_a-z]+):\s*(.*)$`),
 	}
@@ -137,11 +194,10 @@ func (r *configReader) ReadMetadata(
 		module *tfconfig.Module
 		diags  tfconfig.Diagnostics
 
-		cloudProvider provider.CloudProvider
-
 		vm *variableMetadata
 
 		defaultValue *string
+		annotations  map[string]string
 	)
 
 	logger.DebugMessage(
@@ -154,6 +210,9 @@ func (r *configReader) ReadMetadata(
 	if !info.IsDir() {
 		return fmt.Errorf("template path '%s' is not a directory", configPath)
 	}
+	if r.manifest, err = ReadRecipeManifest(configPath); err != nil {
+		return err
+	}
 
 	// load terraform module
 	module, diags = tfconfig.LoadModule(configPath)
@@ -183,6 +242,31 @@ func (r *configReader) ReadMetadata(
 		}
 	}
 
+	if r.manifest != nil {
+		for name := range r.manifest.Variables {
+			if _, exists := module.Variables[name]; !exists {
+				return fmt.Errorf(
+					"%s declares metadata for variable '%s' which is not declared in the templates at '%s'",
+					RecipeManifestFileName, name, configPath)
+			}
+		}
+	}
+
+	// read validation blocks of variables which
+	// are not exposed by the module inspection
+	fileNames := []string{}
+	fileNameSet := make(map[string]bool)
+	for _, tfVar := range module.Variables {
+		if !fileNameSet[tfVar.Pos.Filename] {
+			fileNames = append(fileNames, tfVar.Pos.Filename)
+			fileNameSet[tfVar.Pos.Filename] = true
+		}
+	}
+	sort.Strings(fileNames)
+	if r.variableValidations, err = readVariableValidations(fileNames); err != nil {
+		return err
+	}
+
 	l := len(module.Variables)
 	ll := 0
 
@@ -220,8 +304,50 @@ func (r *configReader) ReadMetadata(
 		}
 	}
 
+	// recipe metadata in the manifest that is
+	// not declared in the templates
+	if r.manifest != nil {
+		if annotations, err = mergeManifestAnnotations(
+			"recipe", r.recipeAnnotations, r.manifest.annotations(),
+		); err != nil {
+			return err
+		}
+		for name, value := range annotations {
+			if err = r.setRecipeAnnotation(name, value); err != nil {
+				return err
+			}
+		}
+		for group, gm := range r.manifest.Groups {
+			if annotations, err = mergeManifestAnnotations(
+				fmt.Sprintf("group '%s'", group), r.groupAnnotations[group], gm.annotations(),
+			); err != nil {
+				return err
+			}
+			for name, value := range annotations {
+				if err = r.setGroupAnnotation(name, group+":"+value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// order the variables group by group so
+	// the fields of a group are contiguous
+	r.variableGroups = groupVariables(variableList, r.groupAnnotations)
+	variableIndex := make(map[string]*variableMetadata)
+	for _, vm = range variableList {
+		variableIndex[vm.name] = vm
+	}
+	variableList = variableList[:0]
+	for _, group := range r.variableGroups {
+		for _, name := range group.Variables {
+			variableList = append(variableList, variableIndex[name])
+		}
+	}
+
 	// populate input form
 	r.inputForm = forms_config.RecipeConfigForms.NewGroup(key + "/" + iaas, r.recipeDescription)
+	r.inputSchema = NewInputSchema(key+"/"+iaas, r.recipeDescription)
 	for _, vm = range variableList {
 
 		defaultValue = nil
@@ -229,21 +355,21 @@ func (r *configReader) ReadMetadata(
 			defaultValue = &vm.defaultValue
 		}
 
-		if len(vm.acceptedValues) > 0 {
-			switch vm.acceptedValues[0] {
-
-			case "$iaas_regions":
-				// special function populates accepted list with cloud regions
-				if cloudProvider, err = provider.NewCloudProvider(iaas); err != nil {
-					return err
-				}
-				vm.acceptedValues = []string{}
-				for _, r := range cloudProvider.GetRegions() {
-					vm.acceptedValues = append(vm.acceptedValues, r.Name)
-				}
+		if len(vm.acceptedValues) > 0 && isAcceptedValuesReference(vm.acceptedValues[0]) {
+			// a values provider such as '$iaas_regions'
+			// populates the accepted list for the iaas
+			if vm.acceptedValues, err = resolveAcceptedValues(vm.acceptedValues[0], iaas, configPath); err != nil {
+				return err
 			}
 		}
 
+		// input fields hold string values and goforms does not
+		// have input types for numbers, bools or json values.
+		// so all variables are string fields whose values are
+		// checked against the variable's type by the type's
+		// inclusion filter and ValidateVariable. the type is
+		// added to the field's tags i.e. "type:number" so it
+		// can be retrieved from the field via FieldType.
 		if _, err = r.inputForm.NewInputField(forms.FieldAttributes{
 			Name:         vm.name,
 			DisplayName:  vm.displayName,
@@ -253,7 +379,7 @@ func (r *configReader) ReadMetadata(
 			Sensitive:    vm.sensitive,
 			EnvVars:      vm.environmentVariables,
 			DependsOn:    vm.dependsOn,
-			Tags:         vm.tags,
+			Tags:         fieldTags(vm.typeName, vm.tags),
 
 			InclusionFilter:             vm.valueInclusionFilter,
 			InclusionFilterErrorMessage: vm.valueInclusionFilterMessage,
@@ -269,6 +395,49 @@ func (r *configReader) ReadMetadata(
 		if vm.key {
 			r.keyFields = append(r.keyFields, vm.name)
 		}
+		r.variableTypes[vm.name] = vm.typeName
+		r.inputSchema.AddProperty(vm.name, vm.inputSchema(), !vm.optional)
+
+		for _, oldName := range vm.renamedFrom {
+			if _, exists := module.Variables[oldName]; exists {
+				return fmt.Errorf(
+					"variable '%s' is renamed from variable '%s' which is still declared",
+					vm.name, oldName)
+			}
+			if newName, exists := r.variableRenames[oldName]; exists {
+				return fmt.Errorf(
+					"variables '%s' and '%s' are both renamed from variable '%s'",
+					newName, vm.name, oldName)
+			}
+			r.variableRenames[oldName] = vm.name
+		}
+		if vm.deprecated {
+			if len(vm.deprecatedMsg) > 0 {
+				r.deprecatedVariables[vm.name] = vm.deprecatedMsg
+			} else {
+				r.deprecatedVariables[vm.name] = fmt.Sprintf("variable '%s' is deprecated", vm.name)
+			}
+		}
+
+		if err = r.readFieldConditions(vm, module.Variables); err != nil {
+			return err
+		}
+	}
+
+	// groups are only described in the
+	// schema if variables have been grouped
+	grouped := false
+	for _, group := range r.variableGroups {
+		grouped = grouped || len(group.Name) > 0
+	}
+	if grouped {
+		for _, group := range r.variableGroups {
+			r.inputSchema.Groups = append(r.inputSchema.Groups, &JSONSchemaGroup{
+				Name:        group.Name,
+				Description: group.Description,
+				Properties:  group.Variables,
+			})
+		}
 	}
 
 	logger.DebugMessage("Loaded recipe with %s", r.inputForm)
@@ -293,7 +462,8 @@ func (r *configReader) readVariableMetadata(
 		ll []string
 		l  string
 		m  [][]string
-		o  int64
+
+		annotations map[string]string
 	)
 
 	vm := &variableMetadata{
@@ -316,6 +486,8 @@ func (r *configReader) readVariableMetadata(
 		order:      maxint,
 		fileName:   strings.TrimSuffix(filepath.Base(tfVar.Pos.Filename), ".tf"),
 		lineNumber: tfVar.Pos.Line,
+
+		annotations: make(map[string]string),
 	}
 
 	// get default value from variable
@@ -323,20 +495,12 @@ func (r *configReader) readVariableMetadata(
 	if tfVar.Default != nil {
 		vm.optional = true
 
-		switch tfVar.Default.(type) {
-		case string:
-			vm.defaultValue = tfVar.Default.(string)
-		case bool:
-			vm.defaultValue = strconv.FormatBool(tfVar.Default.(bool))
-		case int, int64, uint, uint64, float32, float64:
-			vm.defaultValue = fmt.Sprintf("%g", tfVar.Default)
-		default:
-			// need to handle more complex types such as objects, 
-			// arrays and maps. for now force string.
-			vm.defaultValue = fmt.Sprintf("%s", tfVar.Default)
-		}
-		if err != nil {
-			return nil, err
+		// complex values such as lists, maps and
+		// objects are rendered as json literals
+		if vm.defaultValue, err = defaultValueLiteral(tfVar.Default); err != nil {
+			return nil, fmt.Errorf(
+				"invalid default value for variable '%s': %s",
+				tfVar.Name, err.Error())
 		}
 	}
 
@@ -353,29 +517,15 @@ func (r *configReader) readVariableMetadata(
 			l = s.Text()
 
 			if m = r.variableMetadataMatch.FindAllStringSubmatch(l, -1); len(m) > 0 {
-
-				mval := m[0][2]
-				vlen := len(mval)
-
-				switch m[0][1] {
-
-				case "recipe_description":
-					r.recipeDescription = mval
-				case "is_bastion":
-					if vlen > 0 {
-						if ok, err = strconv.ParseBool(mval); err != nil {
-							return nil, err
-						} else if ok {
-							r.isBastion = true
-						}
-					}
-				case "resource_instance_list":
-					if vlen > 0 {
-						r.resourceInstanceList = strings.Split(mval, ",")
+				if _, ok = recipeAnnotationNames[m[0][1]]; ok {
+					if err = r.setRecipeAnnotation(m[0][1], m[0][2]); err != nil {
+						return nil, err
 					}
-				case "resource_instance_data_list":
-					if vlen > 0 {
-						r.resourceInstanceDataList = strings.Split(mval, ",")
+					r.recipeAnnotations[m[0][1]] = m[0][2]
+
+				} else if _, ok = groupAnnotationNames[m[0][1]]; ok {
+					if err = r.setGroupAnnotation(m[0][1], m[0][2]); err != nil {
+						return nil, err
 					}
 				}
 			}
@@ -390,62 +540,10 @@ func (r *configReader) readVariableMetadata(
 
 		if strings.HasPrefix(l, "#") {
 			if m = r.variableMetadataMatch.FindAllStringSubmatch(l, -1); len(m) > 0 {
-
-				mval := m[0][2]
-				vlen := len(mval)
-
-				switch m[0][1] {
-
-				case "display_name":
-					vm.displayName = mval
-				case "accepted_values":
-					if vlen > 0 {
-						vm.acceptedValues = strings.Split(mval, ",")
-					}
-				case "accepted_values_message":
-					vm.acceptedValuesMessage = mval
-				case "value_inclusion_filter":
-					vm.valueInclusionFilter = mval
-				case "value_inclusion_filter_message":
-					vm.valueInclusionFilterMessage = mval
-				case "value_exclusion_filter":
-					vm.valueExclusionFilter = mval
-				case "value_exclusion_filter_message":
-					vm.valueExclusionFilterMessage = mval
-				case "environment_variables":
-					if vlen > 0 {
-						vm.environmentVariables = strings.Split(mval, ",")
-					}
-				case "depends_on":
-					if vlen > 0 {
-						vm.dependsOn = strings.Split(mval, ",")
-					}
-				case "tags":
-					if vlen > 0 {
-						vm.tags = strings.Split(mval, ",")
-					}
-				case "sensitive":
-					if vlen > 0 {
-						if vm.sensitive, err = strconv.ParseBool(mval); err != nil {
-							return nil, err
-						}
-					}
-				case "target_key":
-					if vlen > 0 {
-						if ok, err = strconv.ParseBool(mval); err != nil {
-							return nil, err
-						} else if ok {
-							vm.key = true
-						}
-					}
-				case "order":
-					if vlen > 0 {
-						if o, err = strconv.ParseInt(mval, 10, 32); err != nil {
-							return nil, err
-						}
-						vm.order = int(o)
-					}
+				if err = vm.setAnnotation(m[0][1], m[0][2]); err != nil {
+					return nil, err
 				}
+				vm.annotations[m[0][1]] = m[0][2]
 			}
 
 		} else {
@@ -455,6 +553,34 @@ func (r *configReader) readVariableMetadata(
 		i--
 	}
 
+	// variable metadata in the manifest that
+	// is not declared in the templates
+	if r.manifest != nil {
+		if vmf, exists := r.manifest.Variables[vm.name]; exists {
+			if annotations, err = mergeManifestAnnotations(
+				fmt.Sprintf("variable '%s'", vm.name), vm.annotations, vmf.annotations(),
+			); err != nil {
+				return nil, err
+			}
+			for name, value := range annotations {
+				if err = vm.setAnnotation(name, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// input fields filter values that do not match the
+	// variable's type unless a value filter is provided.
+	// values are always checked against the type when
+	// they are validated via ValidateVariable.
+	if len(vm.valueInclusionFilter) == 0 {
+		vm.valueInclusionFilter, vm.valueInclusionFilterMessage = typeFilter(vm.typeName)
+	}
+	if len(vm.acceptedValues) == 0 && typeKind(vm.typeName) == "bool" {
+		vm.acceptedValues = []string{"true", "false"}
+	}
+
 	logger.TraceMessage(
 		"Loaded variable declared in template file '%s':\n%# v",
 		tfVar.Pos.Filename, vm)
@@ -462,6 +588,201 @@ func (r *configReader) readVariableMetadata(
 	return vm, nil
 }
 
+// parses the @visible_if and @required_if
+// conditions of the given variable
+func (r *configReader) readFieldConditions(
+	vm *variableMetadata,
+	variables map[string]*tfconfig.Variable,
+) error {
+
+	var (
+		err error
+
+		conditions FieldConditions
+	)
+
+	parse := func(annotation, expression string) (*FieldCondition, error) {
+		if len(expression) == 0 {
+			return nil, nil
+		}
+		fc, err := NewFieldCondition(expression)
+		if err != nil {
+			return nil, fmt.Errorf("@%s of variable '%s' is not valid: %s", annotation, vm.name, err.Error())
+		}
+		for _, ref := range fc.References() {
+			if _, exists := variables[ref]; !exists {
+				return nil, fmt.Errorf(
+					"@%s of variable '%s' references variable '%s' which is not declared",
+					annotation, vm.name, ref)
+			}
+			if ref == vm.name {
+				return nil, fmt.Errorf(
+					"@%s of variable '%s' cannot reference the variable itself",
+					annotation, vm.name)
+			}
+		}
+		return fc, nil
+	}
+
+	if conditions.VisibleIf, err = parse("visible_if", vm.visibleIf); err != nil {
+		return err
+	}
+	if conditions.VisibleIf != nil && !vm.optional {
+		// terraform requires a value for variables without
+		// a default so they cannot be hidden from the user
+		return fmt.Errorf(
+			"variable '%s' has a @visible_if condition but no default value",
+			vm.name)
+	}
+	if conditions.RequiredIf, err = parse("required_if", vm.requiredIf); err != nil {
+		return err
+	}
+	if conditions.VisibleIf != nil || conditions.RequiredIf != nil {
+		r.fieldConditions[vm.name] = &conditions
+	}
+	return nil
+}
+
+// annotations that describe the recipe
+var recipeAnnotationNames = map[string]bool{
+	"recipe_description":          true,
+	"is_bastion":                  true,
+	"resource_instance_list":      true,
+	"resource_instance_data_list": true,
+}
+
+// sets recipe metadata from the value of
+// a recipe annotation
+func (r *configReader) setRecipeAnnotation(name, mval string) error {
+
+	var (
+		err error
+		ok  bool
+	)
+	vlen := len(mval)
+
+	switch name {
+
+	case "recipe_description":
+		r.recipeDescription = mval
+	case "is_bastion":
+		if vlen > 0 {
+			if ok, err = strconv.ParseBool(mval); err != nil {
+				return err
+			} else if ok {
+				r.isBastion = true
+			}
+		}
+	case "resource_instance_list":
+		if vlen > 0 {
+			r.resourceInstanceList = strings.Split(mval, ",")
+		}
+	case "resource_instance_data_list":
+		if vlen > 0 {
+			r.resourceInstanceDataList = strings.Split(mval, ",")
+		}
+	}
+	return nil
+}
+
+// sets the description or order of a group
+// from the value of a group annotation
+func (r *configReader) setGroupAnnotation(name, mval string) error {
+
+	group, value, err := parseGroupAnnotation(name, mval)
+	if err != nil {
+		return err
+	}
+	if _, exists := r.groupAnnotations[group]; !exists {
+		r.groupAnnotations[group] = make(map[string]string)
+	}
+	r.groupAnnotations[group][name] = value
+	return nil
+}
+
+// sets variable metadata from the value
+// of a variable annotation
+func (vm *variableMetadata) setAnnotation(name, mval string) error {
+
+	var (
+		err error
+		ok  bool
+		o   int64
+	)
+	vlen := len(mval)
+
+	switch name {
+
+	case "display_name":
+		vm.displayName = mval
+	case "accepted_values":
+		if vlen > 0 {
+			vm.acceptedValues = strings.Split(mval, ",")
+		}
+	case "accepted_values_message":
+		vm.acceptedValuesMessage = mval
+	case "value_inclusion_filter":
+		vm.valueInclusionFilter = mval
+	case "value_inclusion_filter_message":
+		vm.valueInclusionFilterMessage = mval
+	case "value_exclusion_filter":
+		vm.valueExclusionFilter = mval
+	case "value_exclusion_filter_message":
+		vm.valueExclusionFilterMessage = mval
+	case "environment_variables":
+		if vlen > 0 {
+			vm.environmentVariables = strings.Split(mval, ",")
+		}
+	case "depends_on":
+		if vlen > 0 {
+			vm.dependsOn = strings.Split(mval, ",")
+		}
+	case "tags":
+		if vlen > 0 {
+			vm.tags = strings.Split(mval, ",")
+		}
+	case "sensitive":
+		if vlen > 0 {
+			if vm.sensitive, err = strconv.ParseBool(mval); err != nil {
+				return err
+			}
+		}
+	case "target_key":
+		if vlen > 0 {
+			if ok, err = strconv.ParseBool(mval); err != nil {
+				return err
+			} else if ok {
+				vm.key = true
+			}
+		}
+	case "visible_if":
+		vm.visibleIf = strings.TrimSpace(mval)
+	case "required_if":
+		vm.requiredIf = strings.TrimSpace(mval)
+	case "group":
+		vm.group = strings.TrimSpace(mval)
+	case "renamed_from":
+		vm.renamedFrom = []string{}
+		for _, oldName := range strings.Split(mval, ",") {
+			if oldName = strings.TrimSpace(oldName); len(oldName) > 0 {
+				vm.renamedFrom = append(vm.renamedFrom, oldName)
+			}
+		}
+	case "deprecated":
+		vm.deprecated = true
+		vm.deprecatedMsg = strings.TrimSpace(mval)
+	case "order":
+		if vlen > 0 {
+			if o, err = strconv.ParseInt(mval, 10, 32); err != nil {
+				return err
+			}
+			vm.order = int(o)
+		}
+	}
+
+	return nil
+}
+
 func (r *configReader) InputForm() forms.InputForm {
 	return r.inputForm
 }
@@ -470,6 +791,34 @@ func (r *configReader) KeyFields() []string {
 	return r.keyFields
 }
 
+func (r *configReader) VariableTypes() map[string]string {
+	return r.variableTypes
+}
+
+func (r *configReader) VariableValidations() map[string][]*VariableValidation {
+	return r.variableValidations
+}
+
+func (r *configReader) FieldConditions() map[string]*FieldConditions {
+	return r.fieldConditions
+}
+
+func (r *configReader) VariableGroups() []*VariableGroup {
+	return r.variableGroups
+}
+
+func (r *configReader) InputSchema() *JSONSchema {
+	return r.inputSchema
+}
+
+func (r *configReader) VariableRenames() map[string]string {
+	return r.variableRenames
+}
+
+func (r *configReader) DeprecatedVariables() map[string]string {
+	return r.deprecatedVariables
+}
+
 func (r *configReader) IsBastion() bool {
 	return r.isBastion
 }
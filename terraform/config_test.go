@@ -1,7 +1,9 @@
 package terraform_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/appbricks/cloud-builder/terraform"
@@ -55,5 +57,404 @@ var _ = Describe("Config Test", func() {
 				Expect(f.Name()).To(Equal(expectedVariablesInOrder[i]))
 			}
 		})
+
+		It("reads and evaluates the validation blocks of variables", func() {
+
+			validatedTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/validated", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("validated", "aws", validatedTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			validations := reader.VariableValidations()
+			Expect(len(validations["name"])).To(Equal(2))
+			Expect(validations["name"][1].Condition).To(Equal("length(var.name) <= 16"))
+			Expect(validations["name"][1].ErrorMessage).To(Equal("The name must be at most 16 characters."))
+
+			Expect(terraform.ValidateVariable("name", "string", "my-app", validations["name"])).To(Succeed())
+			err = terraform.ValidateVariable("name", "string", "My App", validations["name"])
+			Expect(err).To(MatchError("The name must start with a letter and contain only lowercase letters, digits and dashes."))
+			err = terraform.ValidateVariable("name", "string", "a-very-long-deployment-name", validations["name"])
+			Expect(err).To(MatchError("The name must be at most 16 characters."))
+
+			Expect(terraform.ValidateVariable("instance_type", "string", "t3.small", validations["instance_type"])).To(Succeed())
+			err = terraform.ValidateVariable("instance_type", "string", "m5.large", validations["instance_type"])
+			Expect(err).To(MatchError("The instance type must be one of t3.micro or t3.small."))
+
+			Expect(terraform.ValidateVariable("allowed_cidrs", "list(string)", `["10.0.0.0/8"]`, validations["allowed_cidrs"])).To(Succeed())
+			err = terraform.ValidateVariable("allowed_cidrs", "list(string)", `["10.0.0.0/8","10.1.0.0/16","10.2.0.0/16"]`, validations["allowed_cidrs"])
+			Expect(err).To(MatchError("At most 2 CIDRs are allowed."))
+
+			// functions not available locally are left to terraform
+			Expect(validations["subnet"][0].IsEvaluable()).To(BeFalse())
+			Expect(terraform.ValidateVariable("subnet", "string", "not a cidr", validations["subnet"])).To(Succeed())
+		})
+
+		It("reads the validation blocks of variables declared in json templates", func() {
+
+			jsonTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/json", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("json", "aws", jsonTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			// variables of json and hcl templates are both read
+			form := reader.InputForm()
+			v, err := form.GetFieldValue("subnet")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*v).To(Equal("10.0.0.0/24"))
+			v, err = form.GetFieldValue("region")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*v).To(Equal("us-east-1"))
+
+			validations := reader.VariableValidations()
+			Expect(len(validations["name"])).To(Equal(1))
+			Expect(validations["name"][0].Condition).To(Equal("length(var.name) <= 16"))
+			Expect(validations["name"][0].ErrorMessage).To(Equal("The name must be at most 16 characters."))
+
+			Expect(terraform.ValidateVariable("name", "string", "my-app", validations["name"])).To(Succeed())
+			err = terraform.ValidateVariable("name", "string", "a-very-long-deployment-name", validations["name"])
+			Expect(err).To(MatchError("The name must be at most 16 characters."))
+
+			Expect(len(validations["subnet"])).To(Equal(1))
+			Expect(validations["subnet"][0].IsEvaluable()).To(BeFalse())
+		})
+
+		It("creates input fields validated against the variable types", func() {
+
+			typedTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/typed", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("typed", "aws", typedTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			types := reader.VariableTypes()
+			Expect(types["instance_count"]).To(Equal("number"))
+			Expect(types["enable_logging"]).To(Equal("bool"))
+			Expect(types["allowed_cidrs"]).To(Equal("list(string)"))
+			Expect(types["tags"]).To(Equal("map(string)"))
+			Expect(types["network"]).To(HavePrefix("object("))
+
+			// the types are added to the tags of the input fields
+			Expect(terraform.FieldType([]string{"advanced", terraform.FieldTypeTagPrefix + "list(string)"})).To(Equal("list(string)"))
+			Expect(terraform.FieldType([]string{"advanced"})).To(Equal(""))
+
+			form := reader.InputForm()
+			defaults := map[string]string{
+				"instance_count":  "2",
+				"max_connections": "1000000",
+				"enable_logging":  "true",
+				"allowed_cidrs":   `["10.0.0.0/16","192.168.0.0/24"]`,
+				"tags":            `{"owner":"ops"}`,
+				"disk_size":       "20",
+			}
+			for name, value := range defaults {
+				v, err := form.GetFieldValue(name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(v).NotTo(BeNil())
+				Expect(*v).To(Equal(value))
+			}
+
+			valid := map[string]string{
+				"instance_count": "3",
+				"enable_logging": "false",
+				"allowed_cidrs":  `["0.0.0.0/0"]`,
+				"tags":           `{"team": "dev"}`,
+				"network":        `{"name": "main", "mtu": 1500}`,
+				"disk_size":      "40",
+			}
+			for name, value := range valid {
+				Expect(form.SetFieldValue(name, value)).To(Succeed(), "field '%s'", name)
+			}
+
+			// values are checked against the variable's type
+			// in addition to the variable's value filter
+			Expect(terraform.ValidateVariable("disk_size", "number", "40", nil)).To(Succeed())
+			err = terraform.ValidateVariable("instance_count", "number", "three", nil)
+			Expect(err).To(MatchError("'three' is not a number"))
+			err = terraform.ValidateVariable("allowed_cidrs", "list(string)", `{"cidr": "0.0.0.0/0"}`, nil)
+			Expect(err).To(MatchError("value is not a json encoded list(string)"))
+
+			invalid := map[string]string{
+				"instance_count": "three",
+				"enable_logging": "yes",
+				"allowed_cidrs":  "0.0.0.0/0",
+				"tags":           "team=dev",
+				"network":        "main",
+				"disk_size":      "40.5",
+			}
+			for name, value := range invalid {
+				Expect(form.SetFieldValue(name, value)).NotTo(Succeed(), "field '%s'", name)
+			}
+		})
+
+		It("reads the conditions that determine if a variable is shown or required", func() {
+
+			conditionalTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/conditional", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("conditional", "aws", conditionalTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			conditions := reader.FieldConditions()
+			Expect(len(conditions)).To(Equal(3))
+			Expect(conditions["vpn_type"]).To(BeNil())
+			Expect(conditions["wireguard_port"].RequiredIf).To(BeNil())
+			Expect(conditions["dns_domain"].VisibleIf).To(BeNil())
+
+			types := reader.VariableTypes()
+			visibleIf := conditions["openvpn_cert"].VisibleIf
+			Expect(visibleIf.References()).To(Equal([]string{"vpn_type"}))
+
+			visible, err := visibleIf.Evaluate(map[string]string{"vpn_type": "openvpn"}, types)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(visible).To(BeTrue())
+			visible, err = visibleIf.Evaluate(map[string]string{"vpn_type": "wireguard"}, types)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(visible).To(BeFalse())
+
+			requiredIf := conditions["dns_domain"].RequiredIf
+			Expect(requiredIf.References()).To(Equal([]string{"enable_dns", "vpn_type"}))
+
+			required, err := requiredIf.Evaluate(map[string]string{"enable_dns": "true", "vpn_type": "wireguard"}, types)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(required).To(BeTrue())
+			required, err = requiredIf.Evaluate(map[string]string{"enable_dns": "false", "vpn_type": "wireguard"}, types)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(required).To(BeFalse())
+		})
+
+		It("merges the recipe manifest with the template annotations", func() {
+
+			manifestTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/manifest", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("manifest", "aws", manifestTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			form := reader.InputForm()
+			Expect(form.Description()).To(Equal("Manifest Test Template"))
+			Expect(reader.IsBastion()).To(BeTrue())
+			Expect(reader.ResourceInstanceList()).To(Equal([]string{"aws_instance.bastion"}))
+			Expect(reader.KeyFields()).To(Equal([]string{"name"}))
+
+			fields := form.InputFields()
+			Expect(len(fields)).To(Equal(3))
+			Expect(fields[0].Name()).To(Equal("region"))
+			Expect(fields[0].DisplayName()).To(Equal("Region"))
+			Expect(fields[1].Name()).To(Equal("name"))
+			Expect(fields[1].DisplayName()).To(Equal("Deployment Name"))
+			Expect(fields[2].Name()).To(Equal("instance_type"))
+			Expect(fields[2].AcceptedValues()).To(Equal([]string{"t3.micro", "t3.small"}))
+		})
+
+		It("returns an error when the recipe manifest conflicts with the template annotations", func() {
+
+			conflictTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/manifest-conflict", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("manifest-conflict", "aws", conflictTemplatePath)
+			Expect(err).To(MatchError("variable 'region' declares 'display_name' as 'AWS Region' in recipe.yaml and as '@display_name: Region' in its templates"))
+		})
+
+		It("returns an error when the recipe manifest does not match its schema", func() {
+
+			templatePath, err := os.MkdirTemp("", "manifest")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(templatePath)
+
+			err = os.WriteFile(filepath.Join(templatePath, "main.tf"), []byte(`
+variable "region" {
+  type        = string
+  description = "Region to deploy to"
+}
+`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = os.WriteFile(filepath.Join(templatePath, "recipe.yaml"), []byte(`
+variables:
+  region:
+    display_name: Region
+`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("manifest-schema", "aws", templatePath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("field display_name not found"))
+
+			err = os.WriteFile(filepath.Join(templatePath, "recipe.yaml"), []byte(`
+variables:
+  zone:
+    display-name: Zone
+`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			reader = terraform.NewConfigReader()
+			err = reader.ReadMetadata("manifest-schema", "aws", templatePath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("recipe.yaml declares metadata for variable 'zone' which is not declared"))
+		})
+
+		It("groups variables by their group annotations", func() {
+
+			groupedTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/grouped", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("grouped", "aws", groupedTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			groups := reader.VariableGroups()
+			Expect(len(groups)).To(Equal(4))
+			Expect(groups[0].Name).To(Equal("Network"))
+			Expect(groups[0].Description).To(Equal("Network of the deployment"))
+			Expect(groups[0].Variables).To(Equal([]string{"vpc_cidr", "subnet_cidr"}))
+			Expect(groups[1].Name).To(Equal("Credentials"))
+			Expect(groups[1].Description).To(Equal("Credentials used to access the deployment"))
+			Expect(groups[1].Variables).To(Equal([]string{"admin_user", "admin_password"}))
+			Expect(groups[2].Name).To(Equal(""))
+			Expect(groups[2].Variables).To(Equal([]string{"name"}))
+			Expect(groups[3].Name).To(Equal("VPN"))
+			Expect(groups[3].Description).To(Equal(""))
+			Expect(groups[3].Variables).To(Equal([]string{"vpn_type"}))
+
+			// fields of a group are contiguous in the form
+			names := []string{}
+			for _, f := range reader.InputForm().InputFields() {
+				names = append(names, f.Name())
+			}
+			Expect(names).To(Equal([]string{
+				"vpc_cidr", "subnet_cidr", "admin_user", "admin_password", "name", "vpn_type",
+			}))
+		})
+
+		It("renders the input form as a json schema", func() {
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("basic", "aws", testRecipePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			schema := reader.InputSchema()
+			Expect(schema.Title).To(Equal("basic/aws"))
+			Expect(schema.Description).To(Equal("Basic Test Recipe for AWS"))
+			Expect(schema.Type).To(Equal("object"))
+			Expect(schema.Required).To(Equal([]string{"test_input_5", "test_input_1", "test_input_2"}))
+			Expect(schema.PropertyOrder[:3]).To(Equal([]string{"test_input_5", "test_input_1", "test_input_3"}))
+			Expect(schema.Groups).To(BeNil())
+
+			input1 := schema.Properties["test_input_1"]
+			Expect(input1.Title).To(Equal("Test Input #1"))
+			Expect(input1.Type).To(Equal("string"))
+			Expect(input1.Default).To(BeNil())
+			Expect(input1.Enum).To(Equal([]interface{}{"aa", "bb", "cc", "dd"}))
+			Expect(input1.TargetKey).To(BeTrue())
+
+			input2 := schema.Properties["test_input_2"]
+			Expect(input2.Pattern).To(Equal("^(appbricks)?cookbook"))
+			Expect(input2.Not.Pattern).To(Equal("appbricks$"))
+
+			input3 := schema.Properties["test_input_3"]
+			// defaults of sensitive variables are not exported
+			Expect(input3.Default).To(BeNil())
+			Expect(input3.WriteOnly).To(BeTrue())
+			Expect(input3.Sensitive).To(BeTrue())
+
+			typedTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/typed", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader = terraform.NewConfigReader()
+			err = reader.ReadMetadata("typed", "aws", typedTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := json.Marshal(reader.InputSchema().Properties)
+			Expect(err).NotTo(HaveOccurred())
+
+			properties := make(map[string]map[string]interface{})
+			Expect(json.Unmarshal(data, &properties)).To(Succeed())
+
+			Expect(properties["instance_count"]["type"]).To(Equal("number"))
+			Expect(properties["instance_count"]["default"]).To(Equal(float64(2)))
+			Expect(properties["enable_logging"]["type"]).To(Equal("boolean"))
+			Expect(properties["enable_logging"]["default"]).To(Equal(true))
+			Expect(properties["enable_logging"]["enum"]).To(Equal([]interface{}{true, false}))
+			Expect(properties["allowed_cidrs"]["type"]).To(Equal("array"))
+			Expect(properties["allowed_cidrs"]["items"]).To(Equal(map[string]interface{}{"type": "string"}))
+			Expect(properties["allowed_cidrs"]["default"]).To(Equal([]interface{}{"10.0.0.0/16", "192.168.0.0/24"}))
+			Expect(properties["tags"]["type"]).To(Equal("object"))
+			Expect(properties["tags"]["additionalProperties"]).To(Equal(map[string]interface{}{"type": "string"}))
+			Expect(properties["network"]["type"]).To(Equal("object"))
+			Expect(properties["network"]).NotTo(HaveKey("default"))
+			Expect(properties["disk_size"]["x-value-inclusion-filter"]).To(Equal("^[0-9]+$"))
+			Expect(properties["instance_count"]).NotTo(HaveKey("x-value-inclusion-filter"))
+		})
+
+		It("reads renamed and deprecated variables", func() {
+
+			renamedTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/renamed", sourceDirPath))
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("renamed", "aws", renamedTemplatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reader.VariableRenames()).To(Equal(map[string]string{
+				"region_name":   "region",
+				"instance_size": "instance_type",
+				"size":          "instance_type",
+			}))
+			Expect(reader.DeprecatedVariables()).To(Equal(map[string]string{
+				"legacy_size": "use instance_type to size the deployment",
+			}))
+
+			schema := reader.InputSchema()
+			Expect(schema.Properties["instance_type"].RenamedFrom).To(Equal([]string{"instance_size", "size"}))
+			Expect(schema.Properties["legacy_size"].Deprecated).To(BeTrue())
+		})
+
+		It("returns an error when a variable that may be hidden does not have a default value", func() {
+
+			templatePath, err := os.MkdirTemp("", "conditional")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(templatePath)
+
+			err = os.WriteFile(filepath.Join(templatePath, "main.tf"), []byte(`
+variable "vpn_type" {
+  type    = string
+  default = "wireguard"
+}
+
+# @visible_if: var.vpn_type == "openvpn"
+variable "openvpn_cert" {
+  type = string
+}
+`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			reader := terraform.NewConfigReader()
+			err = reader.ReadMetadata("conditional", "aws", templatePath)
+			Expect(err).To(MatchError("variable 'openvpn_cert' has a @visible_if condition but no default value"))
+		})
+
+		It("does not accept invalid conditions", func() {
+
+			_, err := terraform.NewFieldCondition(`var.vpn_type ==`)
+			Expect(err).To(HaveOccurred())
+			_, err = terraform.NewFieldCondition(`local.vpn_type == "openvpn"`)
+			Expect(err).To(MatchError(`invalid condition 'local.vpn_type == "openvpn"': only variables can be referenced as 'var.<name>'`))
+			_, err = terraform.NewFieldCondition(`cidrhost(var.subnet, 1) != ""`)
+			Expect(err).To(MatchError(`invalid condition 'cidrhost(var.subnet, 1) != ""': function 'cidrhost' is not supported`))
+
+			fc, err := terraform.NewFieldCondition(`var.vpn_type`)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = fc.Evaluate(map[string]string{"vpn_type": "openvpn"}, map[string]string{"vpn_type": "string"})
+			Expect(err).To(MatchError(`condition 'var.vpn_type' did not evaluate to a bool`))
+		})
 	})
 })
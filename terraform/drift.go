@@ -0,0 +1,231 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+/**
+ * Terraform Drift Report
+ */
+
+// a change to an attribute of a resource
+// made outside of terraform
+type AttributeDiff struct {
+	// path of the attribute within the
+	// resource i.e. "tags.Name" or
+	// "ingress[0].cidr_blocks[1]"
+	Path string `json:"path"`
+
+	// the values recorded in the state and the
+	// values read from the cloud. sensitive
+	// values are not reported.
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+
+	Sensitive bool `json:"sensitive"`
+}
+
+// a resource that has changed outside of terraform
+type ResourceDrift struct {
+	Address      string `json:"address"`
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	ProviderName string `json:"providerName"`
+
+	// update if the resource's attributes were
+	// changed or delete if it no longer exists
+	Action PlanAction `json:"action"`
+
+	Attributes []*AttributeDiff `json:"attributes"`
+}
+
+// resources of a deployment that have
+// changed outside of terraform
+type DriftReport struct {
+	TerraformVersion string `json:"terraformVersion"`
+
+	Resources []*ResourceDrift `json:"resources"`
+}
+
+// exit code of 'terraform plan -detailed-exitcode'
+// when the plan succeeded and has changes
+const planHasChangesExitCode = 2
+
+// errors returned by the cli for failed commands
+// that expose the exit code of the command i.e.
+// *exec.ExitError
+type exitCoder interface {
+	ExitCode() int
+}
+
+func newDriftReport(plan *planJSON) *DriftReport {
+
+	report := &DriftReport{
+		TerraformVersion: plan.TerraformVersion,
+		Resources:        []*ResourceDrift{},
+	}
+	for _, rc := range plan.ResourceDrift {
+		if rc.Mode == "data" {
+			continue
+		}
+
+		action := planAction(rc.Change.Actions)
+		if action == ActionNoOp || action == ActionRead {
+			continue
+		}
+
+		drift := &ResourceDrift{
+			Address:      rc.Address,
+			Type:         rc.Type,
+			Name:         rc.Name,
+			ProviderName: rc.ProviderName,
+			Action:       action,
+			Attributes:   []*AttributeDiff{},
+		}
+		if action == ActionDelete {
+			// resource no longer exists in
+			// the cloud so has no attributes
+			report.Resources = append(report.Resources, drift)
+			continue
+		}
+		diffAttributes(
+			"",
+			rc.Change.Before, rc.Change.After,
+			rc.Change.BeforeSensitive, rc.Change.AfterSensitive,
+			&drift.Attributes,
+		)
+		sort.Slice(drift.Attributes, func(i, j int) bool {
+			return drift.Attributes[i].Path < drift.Attributes[j].Path
+		})
+		report.Resources = append(report.Resources, drift)
+	}
+	return report
+}
+
+// out: true if any resources have
+//      changed outside of terraform
+func (d *DriftReport) HasDrift() bool {
+	return len(d.Resources) > 0
+}
+
+// out: the drift of the resource with the given
+//      address or nil if it has not drifted
+func (d *DriftReport) Resource(address string) *ResourceDrift {
+
+	for _, r := range d.Resources {
+		if r.Address == address {
+			return r
+		}
+	}
+	return nil
+}
+
+func (d *DriftReport) String() string {
+	return fmt.Sprintf("Drift: %d resources changed outside of terraform.", len(d.Resources))
+}
+
+// out: true if the error is the exit code terraform
+//      returns when a detailed plan has changes
+func isPlanWithChanges(err error) bool {
+
+	var (
+		exitErr exitCoder
+	)
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == planHasChangesExitCode
+}
+
+// compares the before and after values of a resource
+// and adds a diff for each leaf attribute that differs
+func diffAttributes(
+	path string,
+	before, after interface{},
+	beforeSensitive, afterSensitive interface{},
+	diffs *[]*AttributeDiff,
+) {
+
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	if isSensitive(beforeSensitive) || isSensitive(afterSensitive) {
+		*diffs = append(*diffs, &AttributeDiff{
+			Path:      path,
+			Sensitive: true,
+		})
+		return
+	}
+
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := make(map[string]bool)
+		for k := range beforeMap {
+			keys[k] = true
+		}
+		for k := range afterMap {
+			keys[k] = true
+		}
+		for k := range keys {
+			diffAttributes(
+				joinAttributePath(path, k),
+				beforeMap[k], afterMap[k],
+				sensitiveElem(beforeSensitive, k), sensitiveElem(afterSensitive, k),
+				diffs,
+			)
+		}
+		return
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList && len(beforeList) == len(afterList) {
+		for i := range beforeList {
+			diffAttributes(
+				fmt.Sprintf("%s[%d]", path, i),
+				beforeList[i], afterList[i],
+				sensitiveElem(beforeSensitive, i), sensitiveElem(afterSensitive, i),
+				diffs,
+			)
+		}
+		return
+	}
+
+	*diffs = append(*diffs, &AttributeDiff{
+		Path:   path,
+		Before: before,
+		After:  after,
+	})
+}
+
+func joinAttributePath(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+// out: true if the value is flagged as sensitive
+//      in its entirety
+func isSensitive(sensitive interface{}) bool {
+	s, ok := sensitive.(bool)
+	return ok && s
+}
+
+// out: the sensitive flags of the element with
+//      the given key of a map or list value
+func sensitiveElem(sensitive interface{}, key interface{}) interface{} {
+
+	switch s := sensitive.(type) {
+	case map[string]interface{}:
+		if k, ok := key.(string); ok {
+			return s[k]
+		}
+	case []interface{}:
+		if i, ok := key.(int); ok && i < len(s) {
+			return s[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package terraform
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+/**
+ * Infrastructure as Code Engines
+ */
+
+// an infrastructure as code engine that
+// is compatible with the terraform cli
+type Engine struct {
+	// name of the engine used in
+	// cookbook metadata
+	Name string
+
+	// name of the engine's binary
+	Binary string
+
+	// default registry host of the engine's
+	// providers which is the folder providers
+	// are saved to in a plugin directory
+	RegistryHost string
+}
+
+var (
+	TerraformEngine = &Engine{
+		Name:         "terraform",
+		Binary:       "terraform",
+		RegistryHost: "registry.terraform.io",
+	}
+	OpenTofuEngine = &Engine{
+		Name:         "opentofu",
+		Binary:       "tofu",
+		RegistryHost: "registry.opentofu.org",
+	}
+)
+
+// in: name - the name of the engine. if empty the
+//            terraform engine is returned.
+// out: the engine with the given name
+func LookupEngine(name string) (*Engine, error) {
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", TerraformEngine.Name:
+		return TerraformEngine, nil
+	case OpenTofuEngine.Name, OpenTofuEngine.Binary:
+		return OpenTofuEngine, nil
+	}
+	return nil, fmt.Errorf("infrastructure as code engine '%s' is not supported", name)
+}
+
+// out: the file name of the engine's
+//      binary on the local system
+func (e *Engine) BinaryFileName() string {
+	if runtime.GOOS == "windows" {
+		return e.Binary + ".exe"
+	}
+	return e.Binary
+}
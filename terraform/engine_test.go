@@ -0,0 +1,34 @@
+package terraform_test
+
+import (
+	"github.com/appbricks/cloud-builder/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Engine", func() {
+
+	It("looks up engines by name", func() {
+
+		engine, err := terraform.LookupEngine("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(engine).To(Equal(terraform.TerraformEngine))
+
+		engine, err = terraform.LookupEngine("terraform")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(engine).To(Equal(terraform.TerraformEngine))
+
+		engine, err = terraform.LookupEngine("OpenTofu")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(engine).To(Equal(terraform.OpenTofuEngine))
+		Expect(engine.RegistryHost).To(Equal("registry.opentofu.org"))
+
+		engine, err = terraform.LookupEngine("tofu")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(engine).To(Equal(terraform.OpenTofuEngine))
+
+		_, err = terraform.LookupEngine("pulumi")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,157 @@
+package terraform
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/mevansam/goutils/logger"
+)
+
+/**
+ * Terraform Machine Readable UI Events
+ */
+
+type EventType string
+
+const (
+	EventVersion         EventType = "version"
+	EventLog             EventType = "log"
+	EventDiagnostic      EventType = "diagnostic"
+	EventPlannedChange   EventType = "planned_change"
+	EventResourceDrift   EventType = "resource_drift"
+	EventChangeSummary   EventType = "change_summary"
+	EventOutputs         EventType = "outputs"
+	EventApplyStart      EventType = "apply_start"
+	EventApplyProgress   EventType = "apply_progress"
+	EventApplyComplete   EventType = "apply_complete"
+	EventApplyErrored    EventType = "apply_errored"
+	EventRefreshStart    EventType = "refresh_start"
+	EventRefreshComplete EventType = "refresh_complete"
+	EventProvisionStart  EventType = "provision_start"
+	EventProvisionDone   EventType = "provision_complete"
+	EventProvisionError  EventType = "provision_errored"
+)
+
+// callback invoked for each event emitted by
+// terraform when a runner operation executes
+type EventHandler func(event *Event)
+
+// an event emitted by terraform's -json ui
+type Event struct {
+	Type      EventType `json:"type"`
+	Level     string    `json:"@level"`
+	Message   string    `json:"@message"`
+	Module    string    `json:"@module"`
+	Timestamp time.Time `json:"@timestamp"`
+
+	// resource operation progress for
+	// apply_*, refresh_* and provision_*
+	// events
+	Hook *ResourceHook `json:"hook,omitempty"`
+
+	// planned_change and resource_drift events
+	Change *PlannedChange `json:"change,omitempty"`
+
+	// diagnostic events
+	Diagnostic *Diagnostic `json:"diagnostic,omitempty"`
+
+	// change_summary event which is the
+	// final summary of an operation
+	Changes *ChangeCounts `json:"changes,omitempty"`
+
+	// outputs event
+	Outputs map[string]Output `json:"outputs,omitempty"`
+}
+
+type EventResource struct {
+	Addr            string      `json:"addr"`
+	Module          string      `json:"module"`
+	Resource        string      `json:"resource"`
+	ImpliedProvider string      `json:"implied_provider"`
+	ResourceType    string      `json:"resource_type"`
+	ResourceName    string      `json:"resource_name"`
+	ResourceKey     interface{} `json:"resource_key"`
+}
+
+type ResourceHook struct {
+	Resource EventResource `json:"resource"`
+	Action   string        `json:"action"`
+
+	IDKey   string `json:"id_key,omitempty"`
+	IDValue string `json:"id_value,omitempty"`
+
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty"`
+}
+
+type PlannedChange struct {
+	Resource EventResource `json:"resource"`
+	Action   string        `json:"action"`
+	Reason   string        `json:"reason,omitempty"`
+}
+
+type ChangeCounts struct {
+	Add       int    `json:"add"`
+	Change    int    `json:"change"`
+	Remove    int    `json:"remove"`
+	Operation string `json:"operation"`
+}
+
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address,omitempty"`
+
+	Range *DiagnosticRange `json:"range,omitempty"`
+}
+
+type DiagnosticRange struct {
+	Filename string        `json:"filename"`
+	Start    DiagnosticPos `json:"start"`
+	End      DiagnosticPos `json:"end"`
+}
+
+type DiagnosticPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// reads the stream of json lines written by terraform's
+// -json ui and sends each parsed event to the handler
+func readEvents(input io.Reader, handler EventHandler) {
+
+	var (
+		err error
+	)
+
+	s := bufio.NewScanner(input)
+	// outputs and diagnostics can produce very long lines
+	s.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for s.Scan() {
+		line := s.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		event := &Event{}
+		if err = json.Unmarshal(line, event); err != nil {
+			logger.TraceMessage(
+				"Skipping terraform output that is not a json ui event: %s",
+				string(line))
+			continue
+		}
+		handler(event)
+	}
+	if err = s.Err(); err != nil {
+		logger.ErrorMessage("Error reading terraform json ui events: %s", err.Error())
+	}
+
+	// drain the buffer as otherwise the multi
+	// writer will block indefinitely and cli
+	// command execution will not return
+	_, _ = io.Copy(io.Discard, input)
+}
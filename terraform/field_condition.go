@@ -0,0 +1,138 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+/**
+ * Conditional Recipe Input Fields
+ */
+
+// a condition over the values of other variables that
+// determines if a field is shown or required. conditions
+// are terraform expressions that reference variables as
+// 'var.<name>' i.e. 'var.vpn_type == "wireguard"'.
+type FieldCondition struct {
+	Expression string
+
+	condition hcl.Expression
+
+	// names of the variables the condition references
+	references []string
+}
+
+// conditions declared via a variable's
+// @visible_if and @required_if annotations
+type FieldConditions struct {
+	VisibleIf  *FieldCondition
+	RequiredIf *FieldCondition
+}
+
+// in: expression - the condition expression
+// out: the parsed condition
+func NewFieldCondition(expression string) (*FieldCondition, error) {
+
+	var (
+		err error
+	)
+
+	expr, diags := hclsyntax.ParseExpression([]byte(expression), "condition", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid condition '%s': %s", expression, diags.Error())
+	}
+
+	fc := &FieldCondition{
+		Expression: expression,
+		condition:  expr,
+		references: []string{},
+	}
+	referenced := make(map[string]bool)
+	for _, t := range expr.Variables() {
+		if t.RootName() != "var" || len(t) < 2 {
+			return nil, fmt.Errorf(
+				"invalid condition '%s': only variables can be referenced as 'var.<name>'",
+				expression)
+		}
+		attr, ok := t[1].(hcl.TraverseAttr)
+		if !ok {
+			return nil, fmt.Errorf(
+				"invalid condition '%s': only variables can be referenced as 'var.<name>'",
+				expression)
+		}
+		if !referenced[attr.Name] {
+			fc.references = append(fc.references, attr.Name)
+			referenced[attr.Name] = true
+		}
+	}
+	sort.Strings(fc.references)
+
+	if node, ok := expr.(hclsyntax.Node); ok {
+		hclsyntax.VisitAll(node, func(n hclsyntax.Node) hcl.Diagnostics {
+			if call, ok := n.(*hclsyntax.FunctionCallExpr); ok {
+				if _, exists := validationFunctions[call.Name]; !exists && err == nil {
+					err = fmt.Errorf("invalid condition '%s': function '%s' is not supported", expression, call.Name)
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// out: names of the variables the condition references
+func (fc *FieldCondition) References() []string {
+	return fc.references
+}
+
+// evaluates the condition
+//
+// in: values - the values of the variables keyed by name.
+//              a variable without a value is null.
+// in: types - the type constraints of the variables
+// out: the result of the condition
+func (fc *FieldCondition) Evaluate(values, types map[string]string) (bool, error) {
+
+	var (
+		err error
+		val cty.Value
+	)
+
+	vars := make(map[string]cty.Value)
+	for _, name := range fc.references {
+		value, exists := values[name]
+		if !exists {
+			vars[name] = cty.NullVal(cty.DynamicPseudoType)
+			continue
+		}
+		if val, err = ctyValue(types[name], value); err != nil {
+			// a value that is not valid for its
+			// type does not satisfy any condition
+			vars[name] = cty.NullVal(cty.DynamicPseudoType)
+			continue
+		}
+		vars[name] = val
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(vars),
+		},
+		Functions: validationFunctions,
+	}
+	result, diags := fc.condition.Value(ctx)
+	if diags.HasErrors() {
+		return false, fmt.Errorf("error evaluating condition '%s': %s", fc.Expression, diags.Error())
+	}
+	if result.IsNull() || !result.IsKnown() || result.Type() != cty.Bool {
+		return false, fmt.Errorf("condition '%s' did not evaluate to a bool", fc.Expression)
+	}
+	return result.True(), nil
+}
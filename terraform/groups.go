@@ -0,0 +1,105 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Recipe Variable Groups
+ */
+
+// a section of a recipe's input form which groups
+// related variables i.e. "Network" or "Credentials".
+// variables are added to a group via the @group
+// annotation and groups are described and ordered
+// via the @group_description and @group_order
+// annotations.
+type VariableGroup struct {
+	// name of the group. variables that do not
+	// belong to a group are in the unnamed group.
+	Name        string
+	Description string
+
+	// names of the group's variables in form order
+	Variables []string
+
+	// the group's @group_order
+	order int
+}
+
+// group annotations declared in templates
+var groupAnnotationNames = map[string]bool{
+	"group_description": true,
+	"group_order":       true,
+}
+
+// parses the value of a group annotation which
+// is of the form '<group name>: <value>'
+//
+// in: annotation - the name of the annotation
+// in: value - the value of the annotation
+// out: the group name and the annotation's value
+func parseGroupAnnotation(annotation, value string) (string, string, error) {
+
+	name, groupValue, found := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	groupValue = strings.TrimSpace(groupValue)
+	if !found || len(name) == 0 {
+		return "", "", fmt.Errorf(
+			"annotation '@%s: %s' must be of the form '@%s: <group name>: <value>'",
+			annotation, value, annotation)
+	}
+	if annotation == "group_order" {
+		if _, err := strconv.ParseInt(groupValue, 10, 32); err != nil {
+			return "", "", fmt.Errorf(
+				"annotation '@%s: %s' has an invalid integer value '%s'",
+				annotation, value, groupValue)
+		}
+	}
+	return name, groupValue, nil
+}
+
+// groups the given ordered variables. groups are
+// ordered by their @group_order and groups without
+// an order by the position of their first variable.
+//
+// in: variableList - variables in form order
+// in: groupAnnotations - group annotations keyed
+//                        by group name
+// out: the groups of the variables
+func groupVariables(
+	variableList []*variableMetadata,
+	groupAnnotations map[string]map[string]string,
+) []*VariableGroup {
+
+	const maxint = int(^uint(0) >> 1)
+
+	groups := []*VariableGroup{}
+	groupIndex := make(map[string]*VariableGroup)
+	for _, vm := range variableList {
+		group, exists := groupIndex[vm.group]
+		if !exists {
+			group = &VariableGroup{
+				Name:      vm.group,
+				Variables: []string{},
+				order:     maxint,
+			}
+			if annotations, exists := groupAnnotations[vm.group]; exists {
+				group.Description = annotations["group_description"]
+				if order, err := strconv.ParseInt(annotations["group_order"], 10, 32); err == nil {
+					group.order = int(order)
+				}
+			}
+			groups = append(groups, group)
+			groupIndex[vm.group] = group
+		}
+		group.Variables = append(group.Variables, vm.name)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].order < groups[j].order
+	})
+	return groups
+}
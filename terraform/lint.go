@@ -0,0 +1,401 @@
+package terraform
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+/**
+ * Recipe Template Annotation Linter
+ */
+
+// a problem with an annotation in a recipe template
+type LintFinding struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+
+	// the variable the annotation is attached
+	// to if it is a variable annotation
+	Variable   string `json:"variable,omitempty"`
+	Annotation string `json:"annotation"`
+
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (f *LintFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", f.File, f.Line, f.Severity, f.Message)
+}
+
+// annotations that may appear anywhere in a template
+var fileAnnotations = map[string]bool{
+	"recipe_description":          true,
+	"is_bastion":                  true,
+	"resource_instance_list":      true,
+	"resource_instance_data_list": true,
+	"group_description":           true,
+	"group_order":                 true,
+}
+
+// annotations in the comment block directly
+// above a variable declaration
+var variableAnnotations = map[string]bool{
+	"display_name":                   true,
+	"accepted_values":                true,
+	"accepted_values_message":        true,
+	"value_inclusion_filter":         true,
+	"value_inclusion_filter_message": true,
+	"value_exclusion_filter":         true,
+	"value_exclusion_filter_message": true,
+	"environment_variables":          true,
+	"depends_on":                     true,
+	"tags":                           true,
+	"sensitive":                      true,
+	"target_key":                     true,
+	"visible_if":                     true,
+	"required_if":                    true,
+	"group":                          true,
+	"renamed_from":                   true,
+	"deprecated":                     true,
+	"order":                          true,
+}
+
+// annotations with boolean values
+var boolAnnotations = map[string]bool{
+	"is_bastion": true,
+	"sensitive":  true,
+	"target_key": true,
+}
+
+// matches anything that looks like an annotation
+// including ones the config reader would ignore
+var annotationLintMatch = regexp.MustCompile(`^#\s*@([^\s:]*)(:?)\s*(.*)$`)
+
+// lints the cloud builder annotations in the comments of
+// the terraform templates at the given path
+//
+// in: configPath - path of a recipe's terraform templates
+// out: findings sorted by file and line
+func LintTemplates(configPath string) ([]*LintFinding, error) {
+
+	var (
+		err error
+
+		entries []os.DirEntry
+		file    *os.File
+	)
+
+	module, diags := tfconfig.LoadModule(configPath)
+	for _, d := range diags {
+		if d.Severity == tfconfig.DiagError {
+			return nil, fmt.Errorf(
+				"error parsing terraform templates at '%s': %s (%s)",
+				configPath, d.Summary, d.Detail)
+		}
+	}
+
+	// lines of the comment blocks directly above
+	// variable declarations keyed by file name
+	attached := make(map[string]map[int]string)
+	for _, v := range module.Variables {
+		fileName := filepath.Base(v.Pos.Filename)
+		if _, exists := attached[fileName]; !exists {
+			attached[fileName] = make(map[int]string)
+		}
+		attached[fileName][v.Pos.Line] = v.Name
+	}
+
+	findings := []*LintFinding{}
+	addFinding := func(fileName string, line int, variable, annotation, severity, message string, args ...interface{}) {
+		findings = append(findings, &LintFinding{
+			File:       fileName,
+			Line:       line,
+			Variable:   variable,
+			Annotation: annotation,
+			Severity:   severity,
+			Message:    fmt.Sprintf(message, args...),
+		})
+	}
+
+	// the recipe manifest must match its schema
+	// and only describe declared variables
+	manifest, err := ReadRecipeManifest(configPath)
+	if err != nil {
+		addFinding(RecipeManifestFileName, 0, "", "", SeverityError, "%s", err.Error())
+	} else if manifest != nil {
+		names := []string{}
+		for name := range manifest.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, exists := module.Variables[name]; !exists {
+				addFinding(RecipeManifestFileName, 0, name, "", SeverityError,
+					"manifest declares metadata for variable '%s' which is not declared", name)
+			}
+		}
+	}
+
+	// first variable annotated with each order value
+	orders := make(map[int64]string)
+	keyFields := []string{}
+
+	// variables renamed from each old name
+	renames := make(map[string]string)
+
+	// groups variables are added to and the
+	// first line each group is annotated at
+	groups := make(map[string]bool)
+	groupAnnotations := make(map[string]*LintFinding)
+	if manifest != nil {
+		for _, v := range manifest.Variables {
+			groups[v.Group] = true
+		}
+	}
+
+	if entries, err = os.ReadDir(configPath); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || filepath.Ext(fileName) != ".tf" {
+			continue
+		}
+
+		if file, err = os.Open(filepath.Join(configPath, fileName)); err != nil {
+			return nil, err
+		}
+		lines := []string{}
+		s := bufio.NewScanner(file)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		file.Close()
+		if err = s.Err(); err != nil {
+			return nil, err
+		}
+
+		// resolve the variable each comment line is attached
+		// to the same way the config reader does
+		variableAt := make(map[int]string)
+		for line, name := range attached[fileName] {
+			for i := line - 2; i >= 0 && strings.HasPrefix(lines[i], "#"); i-- {
+				variableAt[i+1] = name
+			}
+		}
+
+		for i, l := range lines {
+			if !strings.HasPrefix(l, "#") {
+				continue
+			}
+			m := annotationLintMatch.FindStringSubmatch(l)
+			if m == nil {
+				continue
+			}
+			line := i + 1
+			name, separator, value := m[1], m[2], strings.TrimSpace(m[3])
+			variable := variableAt[line]
+
+			if !fileAnnotations[name] && !variableAnnotations[name] {
+				if suggestion := closestAnnotation(name); len(suggestion) > 0 {
+					addFinding(fileName, line, variable, name, SeverityError,
+						"unknown annotation '@%s' (did you mean '@%s'?)", name, suggestion)
+				} else {
+					addFinding(fileName, line, variable, name, SeverityError,
+						"unknown annotation '@%s'", name)
+				}
+				continue
+			}
+			if len(separator) == 0 {
+				addFinding(fileName, line, variable, name, SeverityError,
+					"annotation '@%s' must be followed by a ':' and is ignored", name)
+				continue
+			}
+			if variableAnnotations[name] && len(variable) == 0 {
+				addFinding(fileName, line, variable, name, SeverityWarning,
+					"annotation '@%s' is not in the comment block directly above a variable declaration and is ignored", name)
+				continue
+			}
+			if len(value) == 0 {
+				continue
+			}
+
+			if boolAnnotations[name] {
+				if _, err = strconv.ParseBool(value); err != nil {
+					addFinding(fileName, line, variable, name, SeverityError,
+						"annotation '@%s' has an invalid boolean value '%s'", name, value)
+					continue
+				}
+			}
+			switch name {
+			case "order":
+				order, err := strconv.ParseInt(value, 10, 32)
+				if err != nil {
+					addFinding(fileName, line, variable, name, SeverityError,
+						"annotation '@order' has an invalid integer value '%s'", value)
+				} else if other, exists := orders[order]; exists && other != variable {
+					addFinding(fileName, line, variable, name, SeverityWarning,
+						"variable '%s' has the same order %d as variable '%s'", variable, order, other)
+				} else {
+					orders[order] = variable
+				}
+			case "accepted_values":
+				if isAcceptedValuesReference(value) {
+					if provider, _ := parseAcceptedValuesReference(value); !hasAcceptedValuesProvider(provider) {
+						addFinding(fileName, line, variable, name, SeverityError,
+							"accepted values provider '$%s' is not known", provider)
+					}
+				}
+			case "depends_on":
+				for _, d := range strings.Split(value, ",") {
+					if d = strings.TrimSpace(d); len(d) > 0 {
+						if _, exists := module.Variables[d]; !exists {
+							addFinding(fileName, line, variable, name, SeverityError,
+								"variable '%s' depends on variable '%s' which is not declared", variable, d)
+						}
+					}
+				}
+			case "visible_if", "required_if":
+				fc, err := NewFieldCondition(value)
+				if err != nil {
+					addFinding(fileName, line, variable, name, SeverityError,
+						"annotation '@%s' has an invalid condition: %s", name, err.Error())
+					continue
+				}
+				for _, ref := range fc.References() {
+					if _, exists := module.Variables[ref]; !exists {
+						addFinding(fileName, line, variable, name, SeverityError,
+							"condition of variable '%s' references variable '%s' which is not declared", variable, ref)
+					} else if ref == variable {
+						addFinding(fileName, line, variable, name, SeverityError,
+							"condition of variable '%s' references the variable itself", variable)
+					}
+				}
+				if name == "visible_if" && module.Variables[variable].Default == nil {
+					addFinding(fileName, line, variable, name, SeverityError,
+						"variable '%s' has a @visible_if condition but no default value", variable)
+				}
+			case "group":
+				groups[value] = true
+			case "renamed_from":
+				for _, oldName := range strings.Split(value, ",") {
+					if oldName = strings.TrimSpace(oldName); len(oldName) == 0 {
+						continue
+					}
+					if _, exists := module.Variables[oldName]; exists {
+						addFinding(fileName, line, variable, name, SeverityError,
+							"variable '%s' is renamed from variable '%s' which is still declared", variable, oldName)
+					} else if other, exists := renames[oldName]; exists && other != variable {
+						addFinding(fileName, line, variable, name, SeverityError,
+							"variables '%s' and '%s' are both renamed from variable '%s'", other, variable, oldName)
+					} else {
+						renames[oldName] = variable
+					}
+				}
+			case "group_description", "group_order":
+				group, _, err := parseGroupAnnotation(name, value)
+				if err != nil {
+					addFinding(fileName, line, variable, name, SeverityError, "%s", err.Error())
+				} else if _, exists := groupAnnotations[group]; !exists {
+					groupAnnotations[group] = &LintFinding{File: fileName, Line: line, Annotation: name}
+				}
+			case "target_key":
+				if ok, _ := strconv.ParseBool(value); ok {
+					keyFields = append(keyFields, variable)
+				}
+			}
+		}
+	}
+
+	// key fields must have values to
+	// derive a target's key from
+	for _, name := range keyFields {
+		v := module.Variables[name]
+		if v.Default != nil {
+			if s, ok := v.Default.(string); ok && len(s) == 0 {
+				addFinding(filepath.Base(v.Pos.Filename), v.Pos.Line, name, "target_key", SeverityWarning,
+					"target key variable '%s' has an empty default value", name)
+			}
+		}
+		if len(v.Description) == 0 {
+			addFinding(filepath.Base(v.Pos.Filename), v.Pos.Line, name, "target_key", SeverityError,
+				"target key variable '%s' has no description so it is not an input and will not have a value", name)
+		}
+	}
+
+	// described groups should have variables
+	for group, f := range groupAnnotations {
+		if !groups[group] {
+			addFinding(f.File, f.Line, "", f.Annotation, SeverityWarning,
+				"group '%s' does not have any variables", group)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File == findings[j].File {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].File < findings[j].File
+	})
+	return findings, nil
+}
+
+// out: the known annotation closest to the given
+//      unknown annotation or an empty string if
+//      none are similar
+func closestAnnotation(name string) string {
+
+	known := []string{}
+	for _, annotations := range []map[string]bool{fileAnnotations, variableAnnotations} {
+		for a := range annotations {
+			known = append(known, a)
+		}
+	}
+	sort.Strings(known)
+
+	closest := ""
+	minDistance := 3
+	for _, a := range known {
+		if d := editDistance(name, a); d < minDistance {
+			closest = a
+			minDistance = d
+		}
+	}
+	return closest
+}
+
+// out: the levenshtein distance between two strings
+func editDistance(a, b string) int {
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,52 @@
+package terraform_test
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/appbricks/cloud-builder/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lint", func() {
+
+	It("reports no findings for valid annotations", func() {
+
+		testRecipePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/recipes/basic/aws", sourceDirPath))
+		Expect(err).NotTo(HaveOccurred())
+
+		findings, err := terraform.LintTemplates(testRecipePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(BeEmpty())
+	})
+
+	It("reports invalid annotations with their location", func() {
+
+		lintTemplatePath, err := filepath.Abs(fmt.Sprintf("%s/../test/fixtures/templates/lint", sourceDirPath))
+		Expect(err).NotTo(HaveOccurred())
+
+		findings, err := terraform.LintTemplates(lintTemplatePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		messages := []string{}
+		for _, f := range findings {
+			messages = append(messages, f.String())
+		}
+		Expect(messages).To(Equal([]string{
+			"main.tf:2: error: annotation '@is_bastion' has an invalid boolean value 'yes'",
+			"main.tf:5: error: unknown annotation '@acepted_values' (did you mean '@accepted_values'?)",
+			"main.tf:8: warning: target key variable 'name' has an empty default value",
+			"main.tf:15: error: variable 'region' depends on variable 'zone' which is not declared",
+			"main.tf:16: error: annotation '@sensitive' has an invalid boolean value 'maybe'",
+			"main.tf:17: warning: variable 'region' has the same order 1 as variable 'name'",
+			"main.tf:24: error: annotation '@order' has an invalid integer value 'first'",
+			"main.tf:25: error: annotation '@tags' must be followed by a ':' and is ignored",
+			"main.tf:32: warning: annotation '@display_name' is not in the comment block directly above a variable declaration and is ignored",
+			"main.tf:40: error: variable 'storage' has a @visible_if condition but no default value",
+		}))
+		Expect(findings[1].Variable).To(Equal("name"))
+		Expect(findings[1].Annotation).To(Equal("acepted_values"))
+	})
+})
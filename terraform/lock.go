@@ -0,0 +1,153 @@
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * Terraform State Lock Errors
+ */
+
+// error returned when a terraform command fails
+// because the state is locked by another process
+// or by an operation that did not complete
+type StateLockError struct {
+	ID        string
+	Path      string
+	Operation string
+	Who       string
+	Version   string
+	Created   time.Time
+	Info      string
+
+	// the error returned by the cli
+	err error
+}
+
+func (e *StateLockError) Error() string {
+	return fmt.Sprintf(
+		"terraform state is locked by '%s' for operation '%s' since %s (lock id: %s)",
+		e.Who, e.Operation, e.Created.Format(time.RFC3339), e.ID,
+	)
+}
+
+func (e *StateLockError) Unwrap() error {
+	return e.err
+}
+
+// out: how long the state has been locked
+func (e *StateLockError) Age() time.Duration {
+	return time.Since(e.Created)
+}
+
+// summary of the error terraform reports
+// when it is unable to lock the state
+const stateLockErrorSummary = "Error acquiring the state lock"
+
+// lock info fields reported by terraform. terraform
+// prints errors inside a diagnostic box so lines may
+// be prefixed with the box's border i.e. "│   ID: ..."
+var stateLockInfoMatch = regexp.MustCompile(`^[\s│]*(ID|Path|Operation|Who|Version|Created|Info):\s*(.*)$`)
+
+// out: the state lock error described in the given
+//      terraform error output or nil if the output
+//      does not describe a state lock error
+func parseStateLockError(output string, err error) *StateLockError {
+
+	if !strings.Contains(output, stateLockErrorSummary) {
+		return nil
+	}
+
+	lockErr := &StateLockError{err: err}
+	for _, line := range strings.Split(output, "\n") {
+		m := stateLockInfoMatch.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		value := strings.TrimSpace(m[2])
+
+		switch m[1] {
+		case "ID":
+			lockErr.ID = value
+		case "Path":
+			lockErr.Path = value
+		case "Operation":
+			lockErr.Operation = value
+		case "Who":
+			lockErr.Who = value
+		case "Version":
+			lockErr.Version = value
+		case "Created":
+			// terraform formats the time using go's default
+			// time format i.e. "2023-08-01 12:00:00.123 +0000 UTC"
+			if created, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", value); err == nil {
+				lockErr.Created = created
+			}
+		case "Info":
+			lockErr.Info = value
+		}
+	}
+	if len(lockErr.ID) == 0 {
+		// without a lock id the lock
+		// cannot be force unlocked
+		return nil
+	}
+	return lockErr
+}
+
+// maximum amount of error output retained
+const maxErrorCaptureSize = 64 * 1024
+
+// writer for the terraform cli's error output that
+// retains the output of the last command so errors
+// can be inspected. all output is passed through
+// to the given writer.
+type ErrorCapture struct {
+	out io.Writer
+
+	mx  sync.Mutex
+	buf bytes.Buffer
+}
+
+// in: out - the writer to pass error output through to
+func NewErrorCapture(out io.Writer) *ErrorCapture {
+	return &ErrorCapture{
+		out: out,
+	}
+}
+
+func (c *ErrorCapture) Write(p []byte) (int, error) {
+
+	c.mx.Lock()
+	c.buf.Write(p)
+	if c.buf.Len() > maxErrorCaptureSize {
+		// keep the most recent output
+		c.buf.Next(c.buf.Len() - maxErrorCaptureSize)
+	}
+	c.mx.Unlock()
+
+	if c.out == nil {
+		return len(p), nil
+	}
+	return c.out.Write(p)
+}
+
+// discards the captured error output
+func (c *ErrorCapture) Reset() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.buf.Reset()
+}
+
+// out: the error output captured since the last reset
+func (c *ErrorCapture) String() string {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.buf.String()
+}
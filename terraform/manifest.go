@@ -0,0 +1,238 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mevansam/goutils/logger"
+)
+
+/**
+ * Recipe Manifest
+ */
+
+// name of the optional file in a recipe's template
+// directory that declares the recipe's metadata as
+// an alternative to comment annotations
+const RecipeManifestFileName = "recipe.yaml"
+
+// recipe metadata declared in a recipe's manifest.
+// metadata may be declared in the manifest or as an
+// annotation in the recipe's templates. if it is
+// declared in both the values must be the same.
+type RecipeManifest struct {
+	Description              string   `yaml:"description"`
+	IsBastion                *bool    `yaml:"is-bastion"`
+	ResourceInstanceList     []string `yaml:"resource-instance-list"`
+	ResourceInstanceDataList []string `yaml:"resource-instance-data-list"`
+
+	Groups    map[string]*GroupManifest    `yaml:"groups"`
+	Variables map[string]*VariableManifest `yaml:"variables"`
+}
+
+// variable group metadata declared in a
+// recipe's manifest keyed by group name
+type GroupManifest struct {
+	Description string `yaml:"description"`
+	Order       *int   `yaml:"order"`
+}
+
+// variable metadata declared in a recipe's manifest
+type VariableManifest struct {
+	DisplayName                 string   `yaml:"display-name"`
+	AcceptedValues              []string `yaml:"accepted-values"`
+	AcceptedValuesMessage       string   `yaml:"accepted-values-message"`
+	ValueInclusionFilter        string   `yaml:"value-inclusion-filter"`
+	ValueInclusionFilterMessage string   `yaml:"value-inclusion-filter-message"`
+	ValueExclusionFilter        string   `yaml:"value-exclusion-filter"`
+	ValueExclusionFilterMessage string   `yaml:"value-exclusion-filter-message"`
+	EnvironmentVariables        []string `yaml:"environment-variables"`
+	DependsOn                   []string `yaml:"depends-on"`
+	Tags                        []string `yaml:"tags"`
+	Sensitive                   *bool    `yaml:"sensitive"`
+	TargetKey                   *bool    `yaml:"target-key"`
+	VisibleIf                   string   `yaml:"visible-if"`
+	RequiredIf                  string   `yaml:"required-if"`
+	Group                       string   `yaml:"group"`
+	RenamedFrom                 []string `yaml:"renamed-from"`
+	Deprecated                  string   `yaml:"deprecated"`
+	Order                       *int     `yaml:"order"`
+}
+
+// reads the recipe manifest in the given template
+// directory. fields that are not part of the
+// manifest's schema are reported as errors.
+//
+// in: configPath - path of a recipe's terraform templates
+// out: the manifest or nil if the directory does not
+//      have a manifest
+func ReadRecipeManifest(configPath string) (*RecipeManifest, error) {
+
+	var (
+		err error
+
+		data []byte
+	)
+
+	manifestPath := filepath.Join(configPath, RecipeManifestFileName)
+	if data, err = os.ReadFile(manifestPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	manifest := &RecipeManifest{}
+	if err = yaml.UnmarshalStrict(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid recipe manifest '%s': %s", manifestPath, err.Error())
+	}
+	for name, g := range manifest.Groups {
+		if g == nil {
+			return nil, fmt.Errorf(
+				"invalid recipe manifest '%s': group '%s' does not declare any metadata",
+				manifestPath, name)
+		}
+	}
+	for name, v := range manifest.Variables {
+		if v == nil {
+			return nil, fmt.Errorf(
+				"invalid recipe manifest '%s': variable '%s' does not declare any metadata",
+				manifestPath, name)
+		}
+	}
+
+	logger.TraceMessage("Loaded recipe manifest '%s':\n%# v", manifestPath, manifest)
+	return manifest, nil
+}
+
+// out: the recipe annotations declared by
+//      the manifest keyed by annotation name
+func (m *RecipeManifest) annotations() map[string]string {
+
+	annotations := make(map[string]string)
+	if len(m.Description) > 0 {
+		annotations["recipe_description"] = m.Description
+	}
+	if m.IsBastion != nil {
+		annotations["is_bastion"] = strconv.FormatBool(*m.IsBastion)
+	}
+	if len(m.ResourceInstanceList) > 0 {
+		annotations["resource_instance_list"] = strings.Join(m.ResourceInstanceList, ",")
+	}
+	if len(m.ResourceInstanceDataList) > 0 {
+		annotations["resource_instance_data_list"] = strings.Join(m.ResourceInstanceDataList, ",")
+	}
+	return annotations
+}
+
+// out: the group annotations declared by
+//      the manifest keyed by annotation name
+func (g *GroupManifest) annotations() map[string]string {
+
+	annotations := make(map[string]string)
+	if len(g.Description) > 0 {
+		annotations["group_description"] = g.Description
+	}
+	if g.Order != nil {
+		annotations["group_order"] = strconv.Itoa(*g.Order)
+	}
+	return annotations
+}
+
+// out: the variable annotations declared by
+//      the manifest keyed by annotation name
+func (v *VariableManifest) annotations() map[string]string {
+
+	annotations := make(map[string]string)
+	for name, value := range map[string]string{
+		"display_name":                   v.DisplayName,
+		"accepted_values":                strings.Join(v.AcceptedValues, ","),
+		"accepted_values_message":        v.AcceptedValuesMessage,
+		"value_inclusion_filter":         v.ValueInclusionFilter,
+		"value_inclusion_filter_message": v.ValueInclusionFilterMessage,
+		"value_exclusion_filter":         v.ValueExclusionFilter,
+		"value_exclusion_filter_message": v.ValueExclusionFilterMessage,
+		"environment_variables":          strings.Join(v.EnvironmentVariables, ","),
+		"depends_on":                     strings.Join(v.DependsOn, ","),
+		"tags":                           strings.Join(v.Tags, ","),
+		"visible_if":                     v.VisibleIf,
+		"required_if":                    v.RequiredIf,
+		"group":                          v.Group,
+		"renamed_from":                   strings.Join(v.RenamedFrom, ","),
+		"deprecated":                     v.Deprecated,
+	} {
+		if len(value) > 0 {
+			annotations[name] = value
+		}
+	}
+	if v.Sensitive != nil {
+		annotations["sensitive"] = strconv.FormatBool(*v.Sensitive)
+	}
+	if v.TargetKey != nil {
+		annotations["target_key"] = strconv.FormatBool(*v.TargetKey)
+	}
+	if v.Order != nil {
+		annotations["order"] = strconv.Itoa(*v.Order)
+	}
+	return annotations
+}
+
+// merges annotations declared in a recipe's manifest
+// with the annotations declared in its templates
+//
+// in: source - describes what the annotations belong to
+// in: declared - annotations declared in the templates
+// in: manifest - annotations declared in the manifest
+// out: the annotations of the manifest that are not
+//      declared in the templates or an error if an
+//      annotation is declared in both with different
+//      values
+func mergeManifestAnnotations(
+	source string,
+	declared, manifest map[string]string,
+) (map[string]string, error) {
+
+	merged := make(map[string]string)
+	for name, value := range manifest {
+		if declaredValue, exists := declared[name]; exists {
+			if normalizeAnnotation(name, declaredValue) != normalizeAnnotation(name, value) {
+				return nil, fmt.Errorf(
+					"%s declares '%s' as '%s' in %s and as '@%s: %s' in its templates",
+					source, name, value, RecipeManifestFileName, name, declaredValue)
+			}
+			continue
+		}
+		merged[name] = value
+	}
+	return merged, nil
+}
+
+// out: the annotation value in a form that can be
+//      compared with values declared elsewhere
+func normalizeAnnotation(name, value string) string {
+
+	value = strings.TrimSpace(value)
+	switch name {
+	case "is_bastion", "sensitive", "target_key":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return strconv.FormatBool(b)
+		}
+	case "order", "group_order":
+		if i, err := strconv.ParseInt(value, 10, 32); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+	case "accepted_values", "environment_variables", "depends_on", "tags", "renamed_from",
+		"resource_instance_list", "resource_instance_data_list":
+		items := strings.Split(value, ",")
+		for i, item := range items {
+			items[i] = strings.TrimSpace(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return value
+}
@@ -0,0 +1,229 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+/**
+ * Terraform Output Decoding
+ *
+ * Outputs with a null value are decoded as the zero
+ * value of the output's type.
+ */
+
+// out: the output's declared terraform type i.e. "string",
+//      "number", "bool", "list", "set", "tuple", "map" or
+//      "object". if the output does not have a declared
+//      type then the type is inferred from its value.
+func (o Output) TypeName() string {
+
+	switch t := o.Type.(type) {
+	case string:
+		// primitive types are given as the type name
+		if t != "dynamic" {
+			return t
+		}
+	case []interface{}:
+		// collection and structural types are given as
+		// ["list","string"] or ["object",{"a":"string"}]
+		if len(t) > 0 {
+			if name, ok := t[0].(string); ok {
+				return name
+			}
+		}
+	}
+
+	switch o.Value.(type) {
+	case string:
+		return "string"
+	case float64, float32, int, int64, json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return "dynamic"
+}
+
+// out: the output value as a string
+func (o Output) AsString() (string, error) {
+
+	if err := o.checkType("string"); err != nil {
+		return "", err
+	}
+	if o.Value == nil {
+		return "", nil
+	}
+	if v, ok := o.Value.(string); ok {
+		return v, nil
+	}
+	return "", o.valueError("string")
+}
+
+// out: the output value as a number
+func (o Output) AsNumber() (float64, error) {
+
+	if err := o.checkType("number"); err != nil {
+		return 0, err
+	}
+	if o.Value == nil {
+		return 0, nil
+	}
+	switch v := o.Value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	}
+	return 0, o.valueError("number")
+}
+
+// out: the output value as an integer. an error is
+//      returned if the number has a fractional part.
+func (o Output) AsInt() (int, error) {
+
+	var (
+		err error
+		n   float64
+	)
+
+	if n, err = o.AsNumber(); err != nil {
+		return 0, err
+	}
+	if n != float64(int(n)) {
+		return 0, fmt.Errorf("output number value %v is not an integer", n)
+	}
+	return int(n), nil
+}
+
+// out: the output value as a bool
+func (o Output) AsBool() (bool, error) {
+
+	if err := o.checkType("bool"); err != nil {
+		return false, err
+	}
+	if o.Value == nil {
+		return false, nil
+	}
+	if v, ok := o.Value.(bool); ok {
+		return v, nil
+	}
+	return false, o.valueError("bool")
+}
+
+// out: the output value of a list, set or tuple
+func (o Output) AsList() ([]interface{}, error) {
+
+	if err := o.checkType("list", "set", "tuple"); err != nil {
+		return nil, err
+	}
+	if o.Value == nil {
+		return nil, nil
+	}
+	if v, ok := o.Value.([]interface{}); ok {
+		return v, nil
+	}
+	return nil, o.valueError("list")
+}
+
+// out: the output value of a map or object
+func (o Output) AsMap() (map[string]interface{}, error) {
+
+	if err := o.checkType("map", "object"); err != nil {
+		return nil, err
+	}
+	if o.Value == nil {
+		return nil, nil
+	}
+	if v, ok := o.Value.(map[string]interface{}); ok {
+		return v, nil
+	}
+	return nil, o.valueError("map")
+}
+
+// decodes the output value into the given destination
+// in the same way as json.Unmarshal would
+//
+// in: v - pointer to the value to decode into
+func (o Output) Decode(v interface{}) error {
+
+	var (
+		err  error
+		data []byte
+	)
+
+	if data, err = json.Marshal(o.Value); err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf(
+			"output of type '%s' cannot be decoded into a %T: %s",
+			o.TypeName(), v, err.Error())
+	}
+	return nil
+}
+
+// out: the output value formatted as a terraform
+//      input variable value. collection and
+//      structural values are json encoded and
+//      a null value is given as "null".
+func (o Output) ValueAsString() (string, error) {
+
+	if o.Value == nil {
+		return "null", nil
+	}
+	switch o.TypeName() {
+	case "string":
+		return o.AsString()
+	case "number":
+		n, err := o.AsNumber()
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case "bool":
+		b, err := o.AsBool()
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	}
+
+	data, err := json.Marshal(o.Value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// returns an error if the output's type is
+// not one of the given terraform types
+func (o Output) checkType(types ...string) error {
+
+	typeName := o.TypeName()
+	for _, t := range types {
+		if typeName == t {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"output of type '%s' cannot be decoded as a %s",
+		typeName, types[0])
+}
+
+func (o Output) valueError(typeName string) error {
+	return fmt.Errorf(
+		"output value of type %T does not match its declared type '%s'",
+		o.Value, typeName)
+}
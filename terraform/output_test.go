@@ -0,0 +1,153 @@
+package terraform_test
+
+import (
+	"encoding/json"
+
+	"github.com/appbricks/cloud-builder/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Output", func() {
+
+	var (
+		err error
+
+		outputs map[string]terraform.Output
+	)
+
+	BeforeEach(func() {
+		err = json.Unmarshal([]byte(`{
+			"name": { "sensitive": false, "type": "string", "value": "bastion" },
+			"port": { "sensitive": false, "type": "number", "value": 443 },
+			"ratio": { "sensitive": false, "type": "number", "value": 0.5 },
+			"enabled": { "sensitive": false, "type": "bool", "value": true },
+			"zones": { "sensitive": false, "type": [ "list", "string" ], "value": [ "a", "b" ] },
+			"tags": { "sensitive": false, "type": [ "map", "string" ], "value": { "owner": "ops" } },
+			"node": {
+				"sensitive": false,
+				"type": [ "object", { "name": "string", "port": "number" } ],
+				"value": { "name": "node1", "port": 22 }
+			}
+		}`), &outputs)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("decodes outputs of primitive types", func() {
+
+		s, err := outputs["name"].AsString()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(Equal("bastion"))
+
+		i, err := outputs["port"].AsInt()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(i).To(Equal(443))
+
+		n, err := outputs["ratio"].AsNumber()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(0.5))
+
+		b, err := outputs["enabled"].AsBool()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(BeTrue())
+	})
+
+	It("decodes outputs of collection and structural types", func() {
+
+		l, err := outputs["zones"].AsList()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l).To(Equal([]interface{}{"a", "b"}))
+
+		m, err := outputs["tags"].AsMap()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(Equal(map[string]interface{}{"owner": "ops"}))
+
+		node := struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		}{}
+		err = outputs["node"].Decode(&node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Name).To(Equal("node1"))
+		Expect(node.Port).To(Equal(22))
+
+		zones := []string{}
+		err = outputs["zones"].Decode(&zones)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zones).To(Equal([]string{"a", "b"}))
+	})
+
+	It("formats outputs as input variable values", func() {
+
+		expected := map[string]string{
+			"name":    "bastion",
+			"port":    "443",
+			"ratio":   "0.5",
+			"enabled": "true",
+			"zones":   `["a","b"]`,
+			"tags":    `{"owner":"ops"}`,
+			"node":    `{"name":"node1","port":22}`,
+		}
+		for name, value := range expected {
+			s, err := outputs[name].ValueAsString()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s).To(Equal(value))
+		}
+	})
+
+	It("returns an error when the output type does not match", func() {
+
+		_, err = outputs["port"].AsString()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("output of type 'number' cannot be decoded as a string"))
+
+		_, err = outputs["ratio"].AsInt()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("output number value 0.5 is not an integer"))
+
+		_, err = outputs["tags"].AsList()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("output of type 'map' cannot be decoded as a list"))
+
+		port := ""
+		err = outputs["port"].Decode(&port)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("decodes outputs with a declared type and a null value", func() {
+
+		err = json.Unmarshal([]byte(`{
+			"description": { "sensitive": false, "type": "string", "value": null },
+			"port": { "sensitive": false, "type": "number", "value": null },
+			"zones": { "sensitive": false, "type": [ "list", "string" ], "value": null }
+		}`), &outputs)
+		Expect(err).NotTo(HaveOccurred())
+
+		s, err := outputs["description"].AsString()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(Equal(""))
+		n, err := outputs["port"].AsInt()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(0))
+		l, err := outputs["zones"].AsList()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l).To(BeNil())
+
+		for _, name := range []string{"description", "port", "zones"} {
+			s, err = outputs[name].ValueAsString()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s).To(Equal("null"))
+		}
+	})
+
+	It("infers the type of outputs without a declared type", func() {
+
+		output := terraform.Output{Value: float64(8080)}
+		Expect(output.TypeName()).To(Equal("number"))
+
+		s, err := output.ValueAsString()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(Equal("8080"))
+	})
+})
@@ -0,0 +1,184 @@
+package terraform
+
+import (
+	"fmt"
+)
+
+/**
+ * Terraform Plan Summary
+ */
+
+type PlanAction string
+
+const (
+	ActionNoOp    PlanAction = "no-op"
+	ActionCreate  PlanAction = "create"
+	ActionRead    PlanAction = "read"
+	ActionUpdate  PlanAction = "update"
+	ActionDelete  PlanAction = "delete"
+	ActionReplace PlanAction = "replace"
+)
+
+// a change to a single resource
+// that a plan will apply
+type ResourceChange struct {
+	Address      string     `json:"address"`
+	Type         string     `json:"type"`
+	Name         string     `json:"name"`
+	ProviderName string     `json:"providerName"`
+	Action       PlanAction `json:"action"`
+
+	// the reason terraform gave for the action
+	// i.e. "replace_because_tainted"
+	ActionReason string `json:"actionReason,omitempty"`
+
+	// true if the values before or after
+	// the change have sensitive attributes
+	Sensitive bool `json:"sensitive"`
+}
+
+// summary of all resource changes a plan will apply
+type PlanSummary struct {
+	TerraformVersion string `json:"terraformVersion"`
+
+	ResourceChanges []*ResourceChange `json:"resourceChanges"`
+
+	// counts as reported by terraform where
+	// a replace is both an add and a destroy
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// terraform's json representation of a plan
+// as output by 'terraform show -json'
+type planJSON struct {
+	TerraformVersion string `json:"terraform_version"`
+
+	ResourceChanges []resourceChangeJSON `json:"resource_changes"`
+
+	// changes to resources made outside of terraform
+	// detected when the state was refreshed
+	ResourceDrift []resourceChangeJSON `json:"resource_drift"`
+}
+
+type resourceChangeJSON struct {
+	Address      string `json:"address"`
+	Mode         string `json:"mode"`
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	ProviderName string `json:"provider_name"`
+	ActionReason string `json:"action_reason"`
+
+	Change struct {
+		Actions []string `json:"actions"`
+
+		Before interface{} `json:"before"`
+		After  interface{} `json:"after"`
+
+		BeforeSensitive interface{} `json:"before_sensitive"`
+		AfterSensitive  interface{} `json:"after_sensitive"`
+	} `json:"change"`
+}
+
+func newPlanSummary(plan *planJSON) *PlanSummary {
+
+	summary := &PlanSummary{
+		TerraformVersion: plan.TerraformVersion,
+		ResourceChanges:  []*ResourceChange{},
+	}
+	for _, rc := range plan.ResourceChanges {
+		if rc.Mode == "data" {
+			// data sources are only read
+			continue
+		}
+
+		action := planAction(rc.Change.Actions)
+		switch action {
+		case ActionNoOp, ActionRead:
+			continue
+		case ActionCreate:
+			summary.Add++
+		case ActionUpdate:
+			summary.Change++
+		case ActionDelete:
+			summary.Destroy++
+		case ActionReplace:
+			summary.Add++
+			summary.Destroy++
+		}
+
+		summary.ResourceChanges = append(summary.ResourceChanges, &ResourceChange{
+			Address:      rc.Address,
+			Type:         rc.Type,
+			Name:         rc.Name,
+			ProviderName: rc.ProviderName,
+			Action:       action,
+			ActionReason: rc.ActionReason,
+			Sensitive: hasSensitiveValues(rc.Change.BeforeSensitive) ||
+				hasSensitiveValues(rc.Change.AfterSensitive),
+		})
+	}
+	return summary
+}
+
+// out: true if the plan will make any changes
+func (p *PlanSummary) HasChanges() bool {
+	return len(p.ResourceChanges) > 0
+}
+
+// out: the changes for the given action
+func (p *PlanSummary) ChangesFor(action PlanAction) []*ResourceChange {
+
+	changes := []*ResourceChange{}
+	for _, rc := range p.ResourceChanges {
+		if rc.Action == action {
+			changes = append(changes, rc)
+		}
+	}
+	return changes
+}
+
+func (p *PlanSummary) String() string {
+	return fmt.Sprintf(
+		"Plan: %d to add, %d to change, %d to destroy.",
+		p.Add, p.Change, p.Destroy,
+	)
+}
+
+// maps terraform's list of change
+// actions to a single plan action
+func planAction(actions []string) PlanAction {
+
+	switch len(actions) {
+	case 1:
+		return PlanAction(actions[0])
+	case 2:
+		// ["delete","create"] or ["create","delete"]
+		return ActionReplace
+	}
+	return ActionNoOp
+}
+
+// terraform flags sensitive values with a boolean or with
+// a structure matching the value having boolean leaves
+func hasSensitiveValues(sensitive interface{}) bool {
+
+	switch s := sensitive.(type) {
+	case bool:
+		return s
+	case []interface{}:
+		for _, v := range s {
+			if hasSensitiveValues(v) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, v := range s {
+			if hasSensitiveValues(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mevansam/goutils/logger"
+)
+
+// runs a terraform command as a process that is sent an
+// interrupt when the given context is cancelled so that
+// terraform can stop cleanly and release any state locks
+// it holds. if terraform does not stop within the runner's
+// cancel grace period it is killed.
+//
+// in: ctx - the context of the operation
+// in: argList - the terraform command's arguments
+// in: excludeOutputAfter - if not empty the output after the
+//                          line with this pattern is discarded
+func (r *Runner) runProcess(
+	ctx context.Context,
+	argList []string,
+	excludeOutputAfter string,
+) error {
+
+	var (
+		err error
+
+		interruptedAt time.Time
+	)
+
+	if r.errorCapture != nil {
+		r.errorCapture.Reset()
+	}
+
+	cmd := exec.CommandContext(ctx, r.cli.ExecutablePath(), argList...)
+	cmd.Dir = r.cli.WorkingDirectory()
+	cmd.Env = append(os.Environ(), r.env...)
+	cmd.Stderr = r.errorBuffer
+	cmd.Cancel = func() error {
+		interruptedAt = time.Now()
+
+		logger.DebugMessage("Interrupting terraform command: %s", strings.Join(argList, " "))
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			logger.ErrorMessage("Error interrupting terraform: %s", err.Error())
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	// the process is killed if it is still
+	// running once the grace period expires
+	cmd.WaitDelay = r.cancelGracePeriod
+
+	diagnostics := strings.Builder{}
+	if r.eventHandler == nil {
+		if len(excludeOutputAfter) > 0 {
+			cmd.Stdout = newExcludeAfterWriter(r.outputBuffer, excludeOutputAfter)
+		} else {
+			cmd.Stdout = r.outputBuffer
+		}
+		err = cmd.Run()
+
+	} else {
+		// the json ui output is sent to the event
+		// handler instead of the output buffer
+		events, output := io.Pipe()
+		cmd.Stdout = output
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			readEvents(events, r.diagnosticsHandler(&diagnostics))
+		}()
+
+		err = cmd.Run()
+		output.Close()
+		<-done
+	}
+
+	if ctx.Err() != nil {
+		if !interruptedAt.IsZero() && time.Since(interruptedAt) >= r.cancelGracePeriod {
+			logger.WarnMessage(
+				"Terraform did not stop within %s of being interrupted and was killed.",
+				r.cancelGracePeriod)
+		}
+		return ErrCancelled
+	}
+	return r.stateLockError(err, diagnostics.String())
+}
+
+// writes output to the wrapped writer up to and including
+// the line containing the pattern after which all output
+// is discarded
+type excludeAfterWriter struct {
+	writer  io.Writer
+	pattern []byte
+
+	line      []byte
+	excluding bool
+}
+
+func newExcludeAfterWriter(writer io.Writer, pattern string) *excludeAfterWriter {
+	return &excludeAfterWriter{
+		writer:  writer,
+		pattern: []byte(pattern),
+	}
+}
+
+// interface: io.Writer
+
+func (w *excludeAfterWriter) Write(p []byte) (int, error) {
+
+	var (
+		err error
+	)
+
+	n := len(p)
+	for len(p) > 0 && !w.excluding {
+		i := bytes.IndexByte(p, '\n')
+		if i == -1 {
+			// hold back a partial line until it is
+			// known if it contains the pattern
+			w.line = append(w.line, p...)
+			if bytes.Contains(w.line, w.pattern) {
+				w.excluding = true
+				_, err = w.writer.Write(w.line)
+			}
+			break
+		}
+		w.line = append(w.line, p[:i+1]...)
+		p = p[i+1:]
+
+		w.excluding = bytes.Contains(w.line, w.pattern)
+		if _, err = w.writer.Write(w.line); err != nil {
+			return n, err
+		}
+		w.line = w.line[:0]
+	}
+	return n, err
+}
@@ -1,20 +1,37 @@
 package terraform
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/mevansam/goutils/logger"
 	"github.com/mevansam/goutils/run"
 	"github.com/mevansam/goutils/streams"
 )
 
 type Input struct {
 	Optional bool
+
+	// the variable's terraform type constraint i.e.
+	// "number" or "list(string)". string values of
+	// inputs are converted to this type when they
+	// are passed to terraform.
+	Type string
+
+	// rules of the variable's validation blocks
+	// that values are checked against before
+	// they are passed to terraform
+	Validations []*VariableValidation
 }
 
 type Output struct {
@@ -41,10 +58,65 @@ type Runner struct {
 
 	// Terraform backend configuration for recipe
 	backEnd []string
+
+	// Handler for events emitted by terraform's
+	// machine readable ui. when set operations
+	// are run with the -json ui option.
+	eventHandler EventHandler
+
+	// Resource addresses plan and destroy
+	// operations will be limited to
+	targets []string
+
+	// Resource instance addresses the next
+	// plan will replace
+	replace []string
+
+	// Time to wait for terraform to stop cleanly
+	// after it has been interrupted before it is
+	// killed when an operation is cancelled
+	cancelGracePeriod time.Duration
+
+	// Writers for the output of operations that are
+	// run as processes that can be interrupted
+	outputBuffer,
+	errorBuffer io.Writer
+
+	// captures the cli's error output so
+	// failures can be inspected
+	errorCapture *ErrorCapture
+
+	// Time after which a recorded plan can no
+	// longer be approved or applied
+	planExpiry time.Duration
+
+	// Only apply plans that have been approved
+	requireApprovedPlan bool
 }
 
+// error returned when an operation's context
+// is cancelled before terraform completes
+var ErrCancelled = errors.New("terraform operation was cancelled")
+
 const tfPlanFileName = `tf.plan`
 
+const tfDriftPlanFileName = `tf-drift.plan`
+
+// variables file written to the working directory
+// that is passed to terraform commands that need
+// the configuration's variables
+const tfVarsFileName = `tf.tfvars.json`
+
+// folder in the working directory where
+// state is backed up before it is changed
+const tfStateBackupDirName = `state-backups`
+
+const defaultCancelGracePeriod = 30 * time.Second
+
+// line of the apply output after which the outputs,
+// which may contain sensitive values, are written
+const applyCompletePattern = `Apply complete!`
+
 // in: cli - a CLI instance for the running the Terraform binary
 // in: configPath - the Terraform configuration path
 // in: configInputs - list of input variables expected by the Terraform configuration
@@ -65,6 +137,11 @@ func NewRunner(
 
 		env:     []string{},
 		backEnd: []string{},
+		targets: []string{},
+		replace: []string{},
+
+		cancelGracePeriod: defaultCancelGracePeriod,
+		planExpiry:        defaultPlanExpiry,
 	}
 
 	return runner
@@ -99,7 +176,128 @@ func (r *Runner) SetBackend(
 	}
 }
 
+// sets a handler that will receive typed progress events
+// from plan, apply and destroy operations instead of the
+// raw terraform text being written to the cli's output
+func (r *Runner) SetEventHandler(
+	handler EventHandler,
+) {
+	r.eventHandler = handler
+}
+
+// limits plan and destroy operations to the given
+// resource addresses. an empty list clears the
+// targets so that operations apply to all resources.
+func (r *Runner) SetTargets(
+	addresses []string,
+) {
+
+	r.targets = []string{}
+	for _, a := range addresses {
+		r.targets = append(r.targets, fmt.Sprintf("-target=%s", a))
+	}
+}
+
+// sets resource instances that plans will replace
+// even if their configuration has not changed
+func (r *Runner) SetReplace(
+	addresses []string,
+) {
+
+	r.replace = []string{}
+	for _, a := range addresses {
+		r.replace = append(r.replace, fmt.Sprintf("-replace=%s", a))
+	}
+}
+
+// validates that each of the given addresses references
+// a resource or module in the current state. a module
+// or a resource with multiple instances is referenced
+// by the address without an instance key.
+func (r *Runner) ValidateTargets(
+	addresses []string,
+) error {
+
+	var (
+		err error
+
+		stateAddresses []string
+	)
+
+	if len(addresses) == 0 {
+		return nil
+	}
+	if stateAddresses, err = r.runForLines([]string{"state", "list"}); err != nil {
+		return err
+	}
+
+	unknown := []string{}
+	for _, a := range addresses {
+		if !addressInList(a, stateAddresses) {
+			unknown = append(unknown, a)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf(
+			"the following target resources were not found in the state: %s",
+			strings.Join(unknown, ","),
+		)
+	}
+	return nil
+}
+
+// sets how long a cancelled operation waits for terraform
+// to stop after being interrupted before it is killed
+func (r *Runner) SetCancelGracePeriod(
+	gracePeriod time.Duration,
+) {
+	r.cancelGracePeriod = gracePeriod
+}
+
+// sets the writers terraform's output is written to when an
+// operation is run with a cancellable context. such operations
+// are run as processes that are interrupted when the context
+// is cancelled. all other operations are run via the runner's
+// cli, where a cancelled operation waits for it to complete.
+func (r *Runner) SetOutput(
+	outputBuffer,
+	errorBuffer io.Writer,
+) {
+	r.outputBuffer = outputBuffer
+	r.errorBuffer = errorBuffer
+}
+
+// sets the capture the runner's cli writes its
+// error output to. the captured output is used
+// to detect state lock errors.
+func (r *Runner) SetErrorCapture(
+	errorCapture *ErrorCapture,
+) {
+	r.errorCapture = errorCapture
+}
+
+// sets how long a recorded plan can be approved
+// and applied for. a zero duration disables expiry.
+func (r *Runner) SetPlanExpiry(
+	expiry time.Duration,
+) {
+	r.planExpiry = expiry
+}
+
+// when set apply will only apply a plan that has been
+// approved via ApprovePlan. a plan is not created if
+// one does not exist.
+func (r *Runner) SetRequireApprovedPlan(
+	require bool,
+) {
+	r.requireApprovedPlan = require
+}
+
 func (r *Runner) Init() error {
+	return r.InitWithContext(context.Background())
+}
+
+func (r *Runner) InitWithContext(ctx context.Context) error {
 
 	argList := []string{r.configPath ,"init"}
 	if len(r.pluginPath) > 0 {
@@ -107,11 +305,135 @@ func (r *Runner) Init() error {
 	}
 	argList = append(argList, r.backEnd...)
 
-	return r.cli.RunWithEnv(argList, r.env)
+	return r.runWithContext(ctx, argList, "")
+}
+
+// validates the configuration. the configuration is
+// initialized without a backend so the state is not
+// accessed and the working directory can be discarded
+// once validation completes.
+//
+// out: the diagnostics reported by 'terraform validate'
+func (r *Runner) Validate() (*ValidationResult, error) {
+
+	var (
+		err,
+		initErr error
+
+		filter streams.Filter
+		result ValidationResult
+	)
+
+	argList := []string{r.configPath, "init", "-backend=false", "-input=false"}
+	if len(r.pluginPath) > 0 {
+		argList = append(argList, fmt.Sprintf("-plugin-dir=%s", r.pluginPath))
+	}
+	// init fails if the configuration cannot be parsed so
+	// validation continues in order to report diagnostics
+	initErr = r.run(argList)
+
+	// eat all output sent to default
+	// cli output buffer (i.e. stdout)
+	filter.SetBlackHole()
+	r.cli.ApplyFilter(&filter)
+
+	outputBuffer := r.cli.GetPipedOutputBuffer()
+	decodeError := make(chan error, 1)
+
+	go func() {
+		err := json.NewDecoder(outputBuffer).Decode(&result)
+
+		// drain the buffer as otherwise the multi
+		// writer will block indefinitely and cli
+		// command execution will not return
+		_, _ = io.Copy(io.Discard, outputBuffer)
+		decodeError <- err
+	}()
+
+	// terraform exits with an error if the configuration is
+	// invalid in which case the json output has the details
+	err = r.cli.RunWithEnv([]string{r.configPath, "validate", "-json"}, r.env)
+	if decodeErr := <-decodeError; decodeErr != nil {
+		if initErr != nil {
+			return nil, initErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf(
+			"error decoding json output of terraform validate: %s",
+			decodeErr.Error())
+	}
+	if initErr != nil && result.Valid {
+		// the configuration is valid but could not
+		// be initialized i.e. a provider is missing
+		return nil, initErr
+	}
+	return &result, nil
 }
 
+// creates a plan for the given arguments and
+// returns a summary of the changes it will make.
+// the plan is recorded with a hash of the inputs
+// so it can be reviewed and approved before it
+// is applied.
 func (r *Runner) Plan(
 	args map[string]string,
+) (*PlanSummary, error) {
+	return r.PlanWithContext(context.Background(), args)
+}
+
+func (r *Runner) PlanWithContext(
+	ctx context.Context,
+	args map[string]string,
+) (*PlanSummary, error) {
+
+	var (
+		err     error
+		summary *PlanSummary
+	)
+
+	if err = r.plan(ctx, args); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ErrCancelled
+	}
+	if summary, err = r.ShowPlan(); err != nil {
+		return nil, err
+	}
+	if err = r.recordPlan(args, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// out: summary of the changes of the last
+//      plan created in the working directory
+func (r *Runner) ShowPlan() (*PlanSummary, error) {
+
+	var (
+		err  error
+		plan planJSON
+	)
+
+	if err = r.runForJSON(
+		[]string{
+			r.configPath,
+			"show",
+			"-json",
+			filepath.Join(r.cli.WorkingDirectory(), tfPlanFileName),
+		},
+		&plan,
+	); err != nil {
+		return nil, err
+	}
+	return newPlanSummary(&plan), nil
+}
+
+func (r *Runner) plan(
+	ctx context.Context,
+	args map[string]string,
 ) error {
 
 	var (
@@ -119,7 +441,10 @@ func (r *Runner) Plan(
 		argList []string
 	)
 
-	planPath := filepath.Join(r.cli.WorkingDirectory(), tfPlanFileName)
+	// a new plan replaces any recorded plan
+	os.RemoveAll(r.planRecordPath())
+
+	planPath := r.planPath()
 	if argList, err = r.prepareArgList(
 		args,
 		[]string{
@@ -134,55 +459,148 @@ func (r *Runner) Plan(
 	); err != nil {
 		return err
 	}
+	defer r.removeVarsFile()
 
-	err = r.cli.RunWithEnv(argList, r.env)
+	argList = append(append(argList, r.targets...), r.replace...)
+
+	if err = r.runWithContext(ctx, append(argList, r.uiFlags()...), ""); err == ErrCancelled {
+		// discard any partially written plan
+		os.RemoveAll(planPath)
+	}
 	return err
 }
 
-func (r *Runner) Apply(
+// detects resources that have been changed outside
+// of terraform since they were last deployed. the
+// state is not updated.
+//
+// in: args - the configuration's variables
+// out: report of the resources that have drifted
+func (r *Runner) DetectDrift(
 	args map[string]string,
-) (map[string]Output, error) {
+) (*DriftReport, error) {
+	return r.DetectDriftWithContext(context.Background(), args)
+}
+
+func (r *Runner) DetectDriftWithContext(
+	ctx context.Context,
+	args map[string]string,
+) (*DriftReport, error) {
 
 	var (
-		err    error
-		filter streams.Filter
+		err     error
+		argList []string
+
+		plan planJSON
 	)
 
-	// create plan if it does not exist
-	planPath := filepath.Join(r.cli.WorkingDirectory(), tfPlanFileName)
-	if _, err = os.Stat(planPath); os.IsNotExist(err) {
-		err = r.Plan(args)
-	}
-	if err != nil {
+	driftPlanPath := filepath.Join(r.cli.WorkingDirectory(), tfDriftPlanFileName)
+	if argList, err = r.prepareArgList(
+		args,
+		[]string{
+			r.configPath,
+			"plan",
+			"-refresh-only",
+			"-detailed-exitcode",
+			"-input=false",
+			fmt.Sprintf(
+				"-out=%s",
+				driftPlanPath,
+			),
+		},
+	); err != nil {
 		return nil, err
 	}
-	defer os.RemoveAll(planPath)
+	defer r.removeVarsFile()
+	// a refresh-only plan must never be applied
+	// as it would overwrite the deployed state
+	defer os.RemoveAll(driftPlanPath)
 
-	// filter out any outputs from terraform
-	// run which may container sensitive data
-	filter.AddExcludeAfterPattern("Apply complete!")
-	r.cli.ApplyFilter(&filter)
+	if err = r.runWithContext(ctx, append(argList, r.uiFlags()...), ""); err == nil {
+		// exit code 0 means the plan has no changes
+		return &DriftReport{
+			Resources: []*ResourceDrift{},
+		}, nil
+	} else if !isPlanWithChanges(err) {
+		return nil, err
+	}
 
-	if err = r.cli.RunWithEnv(
+	if err = r.runForJSON(
 		[]string{
 			r.configPath,
-			"apply",
-			planPath,
+			"show",
+			"-json",
+			driftPlanPath,
 		},
-		r.env,
+		&plan,
 	); err != nil {
 		return nil, err
 	}
+	return newDriftReport(&plan), nil
+}
+
+func (r *Runner) Apply(
+	args map[string]string,
+) (map[string]Output, error) {
+	return r.ApplyWithContext(context.Background(), args)
+}
+
+func (r *Runner) ApplyWithContext(
+	ctx context.Context,
+	args map[string]string,
+) (map[string]Output, error) {
+
+	var (
+		err error
+	)
+
+	planPath := r.planPath()
+	if r.requireApprovedPlan {
+		var record *PlanRecord
+		if record, err = r.checkPlanRecord(args); err != nil {
+			return nil, err
+		}
+		if !record.Approved {
+			return nil, ErrPlanNotApproved
+		}
+
+	} else if _, err = os.Stat(planPath); os.IsNotExist(err) {
+		// create plan if it does not exist
+		err = r.plan(ctx, args)
+
+	} else if _, err = r.checkPlanRecord(args); err != nil {
+		// an existing plan is only applied if it was
+		// recorded with the inputs being applied
+		logger.DebugMessage("Discarding saved plan: %s", err.Error())
+		err = r.plan(ctx, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.DiscardPlan()
+
+	argList := append([]string{r.configPath, "apply"}, r.uiFlags()...)
+	if err = r.runWithContext(ctx, append(argList, planPath), applyCompletePattern); err != nil {
+		return nil, err
+	}
 	return r.GetOutput()
 }
 
+// validates the given arguments against the configuration's
+// inputs and writes them to a variables file which is added
+// to the given argument list. the variables file should be
+// removed via removeVarsFile once the command has completed.
 func (r *Runner) prepareArgList(
 	args map[string]string,
 	argList []string,
 ) ([]string, error) {
 
 	var (
+		err    error
 		exists bool
+
+		input  Input
+		tfVars []byte
 	)
 
 	required := make(map[string]bool)
@@ -194,13 +612,14 @@ func (r *Runner) prepareArgList(
 
 	for k, v := range args {
 
-		if _, exists = r.configInputs[k]; !exists {
+		if input, exists = r.configInputs[k]; !exists {
 			return nil, fmt.Errorf(
 				"the following argument is not known by the templates: %s", k,
 			)
 		}
-
-		argList = append(append(argList, "-var"), fmt.Sprintf("%s=%s", k, v))
+		if err = ValidateVariable(k, input.Type, v, input.Validations); err != nil {
+			return nil, fmt.Errorf("invalid value for argument '%s': %s", k, err.Error())
+		}
 
 		// remove requried arg if it exists
 		delete(required, k)
@@ -220,7 +639,88 @@ func (r *Runner) prepareArgList(
 				strings.Join(missing, ","),
 			)
 	}
-	return argList, nil
+	if len(args) == 0 {
+		return argList, nil
+	}
+
+	// variables are passed via a file as values given
+	// as command line arguments are visible in process
+	// listings and complex values are hard to quote
+	if tfVars, err = r.TFVarsJSON(args); err != nil {
+		return nil, err
+	}
+	varsPath := filepath.Join(r.cli.WorkingDirectory(), tfVarsFileName)
+	if err = os.WriteFile(varsPath, tfVars, 0600); err != nil {
+		return nil, err
+	}
+	return append(argList, fmt.Sprintf("-var-file=%s", varsPath)), nil
+}
+
+// removes the variables file written by prepareArgList
+func (r *Runner) removeVarsFile() {
+	os.RemoveAll(filepath.Join(r.cli.WorkingDirectory(), tfVarsFileName))
+}
+
+// out: the content of a terraform json variables file for
+//      the given arguments with each value converted to
+//      the type of the corresponding input
+func (r *Runner) TFVarsJSON(args map[string]string) ([]byte, error) {
+
+	var (
+		err   error
+		value interface{}
+	)
+
+	tfVars := make(map[string]interface{})
+	for k, v := range args {
+		if value, err = typedValue(r.configInputs[k].Type, v); err != nil {
+			return nil, fmt.Errorf("invalid value for argument '%s': %s", k, err.Error())
+		}
+		tfVars[k] = value
+	}
+	return json.MarshalIndent(tfVars, "", "  ")
+}
+
+// out: the given string value converted to a value
+//      of the given terraform type constraint
+func typedValue(typeName, value string) (interface{}, error) {
+
+	var (
+		err error
+
+		jsonValue interface{}
+	)
+
+	switch typeKind(typeName) {
+	case "", "string":
+		return value, nil
+
+	case "number":
+		value = strings.TrimSpace(value)
+		if _, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("'%s' is not a number", value)
+		}
+		return json.Number(value), nil
+
+	case "bool":
+		return strconv.ParseBool(value)
+
+	default:
+		// collection, structural and 'any' types are
+		// given as json encoded values
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		if err = decoder.Decode(&jsonValue); err != nil {
+			if typeName == "any" {
+				return value, nil
+			}
+			return nil, fmt.Errorf("value is not a json encoded %s", typeName)
+		}
+		if !matchesTypeKind(typeName, jsonValue) {
+			return nil, fmt.Errorf("value is not a json encoded %s", typeName)
+		}
+		return jsonValue, nil
+	}
 }
 
 func (r *Runner) GetOutput() (map[string]Output, error) {
@@ -299,13 +799,219 @@ func (r *Runner) GetOutput() (map[string]Output, error) {
 	return output, <-decodeError
 }
 
+// out: the deployed state of the configuration
+func (r *Runner) GetState() (*State, error) {
+
+	var (
+		err   error
+		state stateJSON
+	)
+
+	if err = r.runForJSON([]string{"show", "-json"}, &state); err != nil {
+		return nil, err
+	}
+	return newState(&state), nil
+}
+
+// out: all resource instances in the deployed state
+func (r *Runner) StateList() ([]*StateResource, error) {
+
+	var (
+		err   error
+		state *State
+	)
+
+	if state, err = r.GetState(); err != nil {
+		return nil, err
+	}
+	return state.Resources, nil
+}
+
+// out: the resource instance in the deployed
+//      state with the given address
+func (r *Runner) StateShow(address string) (*StateResource, error) {
+
+	var (
+		err   error
+		state *State
+	)
+
+	if state, err = r.GetState(); err != nil {
+		return nil, err
+	}
+	if resource := state.Resource(address); resource != nil {
+		return resource, nil
+	}
+	return nil, fmt.Errorf("resource '%s' was not found in the state", address)
+}
+
+// runs the given terraform command and decodes
+// the json written to its output into v
+func (r *Runner) runForJSON(argList []string, v interface{}) error {
+
+	var (
+		err    error
+		filter streams.Filter
+	)
+
+	// eat all output sent to default
+	// cli output buffer (i.e. stdout)
+	filter.SetBlackHole()
+	r.cli.ApplyFilter(&filter)
+
+	outputBuffer := r.cli.GetPipedOutputBuffer()
+	decodeError := make(chan error, 1)
+
+	go func() {
+		err := json.NewDecoder(outputBuffer).Decode(v)
+
+		// drain the buffer as otherwise the multi
+		// writer will block indefinitely and cli
+		// command execution will not return
+		_, _ = io.Copy(io.Discard, outputBuffer)
+
+		if err != nil && err != io.EOF {
+			decodeError <- fmt.Errorf(
+				"error decoding json output of terraform '%s': %s",
+				strings.Join(argList, " "), err.Error(),
+			)
+		} else {
+			decodeError <- nil
+		}
+	}()
+
+	if err = r.cli.RunWithEnv(argList, r.env); err != nil {
+		return err
+	}
+	return <-decodeError
+}
+
+// runs the given terraform command and
+// returns the lines written to its output
+func (r *Runner) runForLines(argList []string) ([]string, error) {
+
+	var (
+		err    error
+		filter streams.Filter
+	)
+
+	// eat all output sent to default
+	// cli output buffer (i.e. stdout)
+	filter.SetBlackHole()
+	r.cli.ApplyFilter(&filter)
+
+	outputBuffer := r.cli.GetPipedOutputBuffer()
+	lines := []string{}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		s := bufio.NewScanner(outputBuffer)
+		for s.Scan() {
+			if l := strings.TrimSpace(s.Text()); len(l) > 0 {
+				lines = append(lines, l)
+			}
+		}
+		// drain the buffer as otherwise the multi
+		// writer will block indefinitely and cli
+		// command execution will not return
+		_, _ = io.Copy(io.Discard, outputBuffer)
+	}()
+
+	if err = r.cli.RunWithEnv(argList, r.env); err != nil {
+		return nil, err
+	}
+	<-done
+	return lines, nil
+}
+
+// runs the given terraform command and
+// returns all the output it wrote to stdout
+func (r *Runner) runForOutput(argList []string) ([]byte, error) {
+
+	var (
+		err    error
+		filter streams.Filter
+	)
+
+	// eat all output sent to default
+	// cli output buffer (i.e. stdout)
+	filter.SetBlackHole()
+	r.cli.ApplyFilter(&filter)
+
+	outputBuffer := r.cli.GetPipedOutputBuffer()
+	output := []byte{}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		var (
+			err error
+		)
+
+		// reading all of the buffer also ensures the multi
+		// writer does not block indefinitely and the cli
+		// command execution returns
+		if output, err = io.ReadAll(outputBuffer); err != nil {
+			logger.ErrorMessage("Error reading terraform output: %s", err.Error())
+			_, _ = io.Copy(io.Discard, outputBuffer)
+		}
+	}()
+
+	if err = r.cli.RunWithEnv(argList, r.env); err != nil {
+		return nil, err
+	}
+	<-done
+	return output, nil
+}
+
+// out: true if the address references a resource in the list
+//      or a module or resource with instances in the list
+func addressInList(address string, addresses []string) bool {
+
+	for _, a := range addresses {
+		if a == address ||
+			strings.HasPrefix(a, address+".") ||
+			strings.HasPrefix(a, address+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// removes the plan in the working directory
+// if one exists so it will not be applied
+func (r *Runner) DiscardPlan() {
+	os.RemoveAll(r.planPath())
+	os.RemoveAll(r.planRecordPath())
+}
+
+// releases the state lock with the given id. this
+// should only be used to release a lock held by an
+// operation that did not complete as releasing a
+// lock held by a running operation may corrupt the
+// state.
+//
+// in: lockID - the id of the lock to release
+func (r *Runner) ForceUnlock(lockID string) error {
+
+	if len(lockID) == 0 {
+		return fmt.Errorf("a lock id is required to release the state lock")
+	}
+	return r.cli.RunWithEnv([]string{"force-unlock", "-force", lockID}, r.env)
+}
+
+// Deprecated: tainting changes the state immediately. use
+// SetReplace to replace resources when a plan is applied.
 func (r *Runner) Taint(resources []string) error {
 
 	var (
 		err error
 	)
 	// ensure plan file if it exists is removed
-	os.RemoveAll(filepath.Join(r.cli.WorkingDirectory(), tfPlanFileName))
+	r.DiscardPlan()
 
 	for _, resource := range resources {
 		if err = r.cli.RunWithEnv([]string{"taint", resource}, r.env); err != nil {
@@ -315,14 +1021,290 @@ func (r *Runner) Taint(resources []string) error {
 	return nil
 }
 
-func (r *Runner) Destroy() error {
+// saves a copy of the current state to the backups folder
+// in the working directory. the state is pulled from the
+// configured backend so remote state is also backed up.
+//
+// out: the path of the state backup file
+func (r *Runner) BackupState() (string, error) {
+
+	var (
+		err   error
+		state []byte
+	)
+
+	if state, err = r.runForOutput([]string{"state", "pull"}); err != nil {
+		return "", err
+	}
+
+	backupDir := filepath.Join(r.cli.WorkingDirectory(), tfStateBackupDirName)
+	if err = os.MkdirAll(backupDir, 0700); err != nil {
+		return "", err
+	}
+	backupPath := filepath.Join(
+		backupDir,
+		fmt.Sprintf("terraform-%d.tfstate", time.Now().UnixNano()),
+	)
+	if err = os.WriteFile(backupPath, state, 0600); err != nil {
+		return "", err
+	}
+	logger.DebugMessage("Runner.BackupState(): state backed up to '%s'", backupPath)
+
+	return backupPath, nil
+}
+
+// imports an existing cloud resource into the state
+//
+// in: args - the configuration's variables
+// in: address - the resource address to import to
+// in: id - the cloud identifier of the resource to import
+func (r *Runner) Import(
+	args map[string]string,
+	address, id string,
+) error {
+
+	var (
+		err     error
+		argList []string
+	)
+
+	if argList, err = r.prepareArgList(
+		args,
+		[]string{
+			r.configPath,
+			"import",
+			"-input=false",
+		},
+	); err != nil {
+		return err
+	}
+	defer r.removeVarsFile()
+
+	if _, err = r.BackupState(); err != nil {
+		return err
+	}
+	r.DiscardPlan()
+
+	return r.runStateCommand(append(argList, address, id))
+}
+
+// moves a resource in the state to a new address
+// so it is not recreated when its address changes
+//
+// in: source - the current address of the resource
+// in: destination - the new address of the resource
+func (r *Runner) StateMove(source, destination string) error {
+
+	var (
+		err error
+	)
+
+	if _, err = r.BackupState(); err != nil {
+		return err
+	}
+	r.DiscardPlan()
+
+	return r.runStateCommand([]string{"state", "mv", source, destination})
+}
+
+// removes resources from the state without
+// destroying the corresponding cloud resources
+//
+// in: addresses - the addresses of the resources to remove
+func (r *Runner) StateRemove(addresses []string) error {
+
+	var (
+		err error
+	)
+
+	if len(addresses) == 0 {
+		return nil
+	}
+	if _, err = r.BackupState(); err != nil {
+		return err
+	}
+	r.DiscardPlan()
+
+	return r.runStateCommand(append([]string{"state", "rm"}, addresses...))
+}
+
+func (r *Runner) Destroy(
+	args map[string]string,
+) error {
+	return r.DestroyWithContext(context.Background(), args)
+}
+
+func (r *Runner) DestroyWithContext(
+	ctx context.Context,
+	args map[string]string,
+) error {
+
+	var (
+		err     error
+		argList []string
+	)
+
 	// ensure plan file if it exists is removed
-	os.RemoveAll(filepath.Join(r.cli.WorkingDirectory(), tfPlanFileName))
-
-	return r.cli.RunWithEnv([]string{
-		r.configPath, 
-		"apply", 
-		"-destroy", 
-		"-auto-approve",
-	}, r.env)
+	r.DiscardPlan()
+
+	if argList, err = r.prepareArgList(
+		args,
+		[]string{
+			r.configPath,
+			"apply",
+			"-destroy",
+			"-auto-approve",
+		},
+	); err != nil {
+		return err
+	}
+	defer r.removeVarsFile()
+
+	argList = append(argList, r.targets...)
+	return r.runWithContext(ctx, append(argList, r.uiFlags()...), "")
+}
+
+// out: terraform ui options for the
+//      runner's output mode
+func (r *Runner) uiFlags() []string {
+	if r.eventHandler != nil {
+		return []string{"-json"}
+	}
+	return []string{}
+}
+
+// runs a terraform command streaming json ui
+// events to the event handler if one is set
+func (r *Runner) run(argList []string) error {
+
+	var (
+		err    error
+		filter streams.Filter
+	)
+
+	if r.errorCapture != nil {
+		r.errorCapture.Reset()
+	}
+	if r.eventHandler == nil {
+		return r.stateLockError(r.cli.RunWithEnv(argList, r.env), "")
+	}
+
+	// the json ui output is sent to the event
+	// handler instead of the cli output buffer
+	filter.SetBlackHole()
+	r.cli.ApplyFilter(&filter)
+
+	outputBuffer := r.cli.GetPipedOutputBuffer()
+	done := make(chan struct{})
+
+	// error diagnostics are retained as with the
+	// json ui errors are not written to stderr
+	diagnostics := strings.Builder{}
+
+	go func() {
+		defer close(done)
+		readEvents(outputBuffer, r.diagnosticsHandler(&diagnostics))
+	}()
+
+	err = r.cli.RunWithEnv(argList, r.env)
+	<-done
+	return r.stateLockError(err, diagnostics.String())
+}
+
+// out: an event handler that records the error diagnostics
+//      of the events it sends to the runner's event handler
+func (r *Runner) diagnosticsHandler(diagnostics *strings.Builder) EventHandler {
+
+	return func(event *Event) {
+		if event.Type == EventDiagnostic &&
+			event.Diagnostic != nil && event.Diagnostic.Severity == "error" {
+
+			diagnostics.WriteString(event.Diagnostic.Summary)
+			diagnostics.WriteRune('\n')
+			diagnostics.WriteString(event.Diagnostic.Detail)
+			diagnostics.WriteRune('\n')
+		}
+		r.eventHandler(event)
+	}
+}
+
+// runs a command that changes the state
+func (r *Runner) runStateCommand(argList []string) error {
+
+	if r.errorCapture != nil {
+		r.errorCapture.Reset()
+	}
+	return r.stateLockError(r.cli.RunWithEnv(argList, r.env), "")
+}
+
+// out: a StateLockError if the error output of a failed
+//      command reports that the state is locked otherwise
+//      the given error
+func (r *Runner) stateLockError(err error, errorOutput string) error {
+
+	if err == nil {
+		return nil
+	}
+	if r.errorCapture != nil {
+		errorOutput += r.errorCapture.String()
+	}
+	if lockErr := parseStateLockError(errorOutput, err); lockErr != nil {
+		logger.DebugMessage("Terraform state is locked: %# v", lockErr)
+		return lockErr
+	}
+	return err
+}
+
+// runs a terraform command that is interrupted if the
+// given context is cancelled before the command completes.
+// the command is only run as a process that can be
+// interrupted if the context can be cancelled and the
+// runner's output writers have been set. otherwise it
+// is run via the runner's cli.
+//
+// in: ctx - the context of the operation
+// in: argList - the terraform command's arguments
+// in: excludeOutputAfter - if not empty the output after the
+//                          line with this pattern is discarded
+func (r *Runner) runWithContext(
+	ctx context.Context,
+	argList []string,
+	excludeOutputAfter string,
+) error {
+
+	var (
+		err error
+	)
+
+	if ctx.Err() != nil {
+		return ErrCancelled
+	}
+	if r.outputBuffer != nil && ctx.Done() != nil {
+		return r.runProcess(ctx, argList, excludeOutputAfter)
+	}
+	if len(excludeOutputAfter) > 0 {
+		// filter out any outputs from terraform
+		// run which may container sensitive data
+		filter := streams.Filter{}
+		filter.AddExcludeAfterPattern(excludeOutputAfter)
+		r.cli.ApplyFilter(&filter)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- r.run(argList)
+	}()
+
+	select {
+	case err = <-result:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.WarnMessage(
+		"The terraform cli cannot be interrupted. Waiting for the cancelled command to complete: %s",
+		strings.Join(argList, " "))
+
+	<-result
+	return ErrCancelled
 }
@@ -1,10 +1,17 @@
 package terraform_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/appbricks/cloud-builder/terraform"
+	"github.com/mevansam/goutils/run"
+	"github.com/onsi/gomega/gbytes"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -25,15 +32,21 @@ var _ = Describe("Runner", func() {
 		testRecipePath,
 		testPluginPath string
 
-		output map[string]terraform.Output
+		output  map[string]terraform.Output
+		summary *terraform.PlanSummary
 
 		planRequestKey,
+		showPlanRequestKey,
 		applyRequestKey,
 		outputRequestKey string
 	)
 
 	BeforeEach(func() {
 		cli = utils_mocks.NewFakeCLI(&outputBuffer, &errorBuffer)
+
+		// variables files are written to the cli's working directory
+		err = os.MkdirAll(cli.WorkingDirectory(), 0755)
+		Expect(err).NotTo(HaveOccurred())
 	})
 
 	Describe("terraform configuration execution", func() {
@@ -49,7 +62,7 @@ var _ = Describe("Runner", func() {
 				testRecipePath,
 				testPluginPath,
 				map[string]terraform.Input{
-					"test_input": {false},
+					"test_input": {Optional: false},
 				})
 
 			planRequestKey = cli.AddFakeResponse(
@@ -58,7 +71,7 @@ var _ = Describe("Runner", func() {
 					"plan",
 					"-input=false",
 					"-out=/goutils/test/cli/workingdirectory/tf.plan",
-					"-var", "test_input=arg value 1",
+					"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
 				},
 				[]string{
 					"envvar1=envvar value 1",
@@ -69,6 +82,96 @@ var _ = Describe("Runner", func() {
 				nil,
 			)
 
+			showPlanRequestKey = cli.AddFakeResponse(
+				[]string{
+					"-chdir=" + testRecipePath,
+					"show",
+					"-json",
+					"/goutils/test/cli/workingdirectory/tf.plan",
+				},
+				[]string{
+					"envvar1=envvar value 1",
+					"envvar2=envvar value 2",
+				},
+				`{
+					"format_version": "1.2",
+					"terraform_version": "1.5.0",
+					"resource_changes": [
+						{
+							"address": "aws_instance.bastion",
+							"mode": "managed",
+							"type": "aws_instance",
+							"name": "bastion",
+							"provider_name": "registry.terraform.io/hashicorp/aws",
+							"change": {
+								"actions": ["delete", "create"],
+								"before_sensitive": {},
+								"after_sensitive": {}
+							},
+							"action_reason": "replace_because_tainted"
+						},
+						{
+							"address": "aws_security_group.bastion",
+							"mode": "managed",
+							"type": "aws_security_group",
+							"name": "bastion",
+							"provider_name": "registry.terraform.io/hashicorp/aws",
+							"change": {
+								"actions": ["update"],
+								"before_sensitive": { "ingress": [ {} ] },
+								"after_sensitive": { "ingress": [ {} ] }
+							}
+						},
+						{
+							"address": "aws_ami.ubuntu",
+							"mode": "data",
+							"type": "aws_ami",
+							"name": "ubuntu",
+							"provider_name": "registry.terraform.io/hashicorp/aws",
+							"change": {
+								"actions": ["read"]
+							}
+						},
+						{
+							"address": "aws_vpc.main",
+							"mode": "managed",
+							"type": "aws_vpc",
+							"name": "main",
+							"provider_name": "registry.terraform.io/hashicorp/aws",
+							"change": {
+								"actions": ["no-op"]
+							}
+						},
+						{
+							"address": "random_password.admin",
+							"mode": "managed",
+							"type": "random_password",
+							"name": "admin",
+							"provider_name": "registry.terraform.io/hashicorp/random",
+							"change": {
+								"actions": ["create"],
+								"before_sensitive": false,
+								"after_sensitive": { "result": true }
+							}
+						},
+						{
+							"address": "aws_eip.old",
+							"mode": "managed",
+							"type": "aws_eip",
+							"name": "old",
+							"provider_name": "registry.terraform.io/hashicorp/aws",
+							"change": {
+								"actions": ["delete"],
+								"before_sensitive": {},
+								"after_sensitive": false
+							}
+						}
+					]
+				}`,
+				"",
+				nil,
+			)
+
 			applyRequestKey = cli.AddFakeResponse(
 				[]string{
 					"-chdir=" + testRecipePath,
@@ -175,11 +278,76 @@ var _ = Describe("Runner", func() {
 			})
 		})
 
+		Context("validate", func() {
+
+			var (
+				initArgs []string
+			)
+
+			BeforeEach(func() {
+				initArgs = []string{
+					"-chdir=" + testRecipePath,
+					"init",
+					"-backend=false",
+					"-input=false",
+					"-plugin-dir=" + testPluginPath,
+				}
+			})
+
+			It("reports a valid configuration", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					initArgs, []string{},
+					"Terraform has been successfully initialized!", "", nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{"-chdir=" + testRecipePath, "validate", "-json"}, []string{},
+					`{"format_version":"1.0","valid":true,"error_count":0,"warning_count":0,"diagnostics":[]}`, "", nil,
+				))
+
+				result, err := runner.Validate()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+				Expect(result.Valid).To(BeTrue())
+				Expect(result.Diagnostics).To(BeEmpty())
+			})
+
+			It("reports the diagnostics of an invalid configuration", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					initArgs, []string{},
+					"", "Error: Unsupported block type", fmt.Errorf("exit status 1"),
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{"-chdir=" + testRecipePath, "validate", "-json"}, []string{},
+					validateJSON, "", exitError{code: 1},
+				))
+
+				result, err := runner.Validate()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+				Expect(result.Valid).To(BeFalse())
+				Expect(result.ErrorCount).To(Equal(1))
+				Expect(result.WarningCount).To(Equal(1))
+
+				errs := result.DiagnosticsWithSeverity(terraform.SeverityError)
+				Expect(len(errs)).To(Equal(1))
+				Expect(errs[0].Summary).To(Equal("Unsupported block type"))
+				Expect(errs[0].Position()).To(Equal("main.tf:12,1"))
+				Expect(errs[0].String()).To(Equal("error: Unsupported block type (main.tf:12,1)"))
+
+				warnings := result.DiagnosticsWithSeverity(terraform.SeverityWarning)
+				Expect(len(warnings)).To(Equal(1))
+				Expect(warnings[0].Position()).To(Equal(""))
+			})
+		})
+
 		Context("plan", func() {
 
 			It("executes 'terraform plan' with given environment and variables and reads output", func() {
 
 				cli.ExpectFakeRequest(planRequestKey)
+				cli.ExpectFakeRequest(showPlanRequestKey)
 
 				runner.SetEnv(
 					map[string]string{
@@ -187,14 +355,55 @@ var _ = Describe("Runner", func() {
 						"envvar2": "envvar value 2",
 					},
 				)
-				err = runner.Plan(
+				summary, err = runner.Plan(
 					map[string]string{
 						"test_input": "arg value 1",
 					},
 				)
 				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
 				Expect(outputBuffer.String()).To(HavePrefix("Plan: 1 to add, 0 to change, 0 to destroy."))
 				Expect(errorBuffer.String()).To(Equal(""))
+
+				Expect(summary.TerraformVersion).To(Equal("1.5.0"))
+				Expect(summary.String()).To(Equal("Plan: 2 to add, 1 to change, 2 to destroy."))
+				Expect(summary.HasChanges()).To(BeTrue())
+				Expect(summary.ResourceChanges).To(Equal([]*terraform.ResourceChange{
+					{
+						Address:      "aws_instance.bastion",
+						Type:         "aws_instance",
+						Name:         "bastion",
+						ProviderName: "registry.terraform.io/hashicorp/aws",
+						Action:       terraform.ActionReplace,
+						ActionReason: "replace_because_tainted",
+						Sensitive:    false,
+					},
+					{
+						Address:      "aws_security_group.bastion",
+						Type:         "aws_security_group",
+						Name:         "bastion",
+						ProviderName: "registry.terraform.io/hashicorp/aws",
+						Action:       terraform.ActionUpdate,
+						Sensitive:    false,
+					},
+					{
+						Address:      "random_password.admin",
+						Type:         "random_password",
+						Name:         "admin",
+						ProviderName: "registry.terraform.io/hashicorp/random",
+						Action:       terraform.ActionCreate,
+						Sensitive:    true,
+					},
+					{
+						Address:      "aws_eip.old",
+						Type:         "aws_eip",
+						Name:         "old",
+						ProviderName: "registry.terraform.io/hashicorp/aws",
+						Action:       terraform.ActionDelete,
+						Sensitive:    false,
+					},
+				}))
+				Expect(len(summary.ChangesFor(terraform.ActionReplace))).To(Equal(1))
 			})
 		})
 
@@ -314,86 +523,210 @@ var _ = Describe("Runner", func() {
 			})
 		})
 
-		Context("output", func() {
+		Context("approved plans", func() {
 
-			It("executes 'terraform output' and reads the output", func() {
+			var (
+				planPath string
+				args     map[string]string
+			)
+
+			BeforeEach(func() {
+				runner.SetEnv(
+					map[string]string{
+						"envvar1": "envvar value 1",
+						"envvar2": "envvar value 2",
+					},
+				)
+				args = map[string]string{
+					"test_input": "arg value 1",
+				}
+
+				// the fake cli does not create plans
+				planPath = filepath.Join(cli.WorkingDirectory(), "tf.plan")
+				err = os.WriteFile(planPath, []byte("plan"), 0600)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				runner.DiscardPlan()
+			})
+
+			It("applies only a plan that has been approved", func() {
+				runner.SetRequireApprovedPlan(true)
 
+				cli.ExpectFakeRequest(planRequestKey)
+				cli.ExpectFakeRequest(showPlanRequestKey)
+				summary, err = runner.Plan(args)
+				Expect(err).NotTo(HaveOccurred())
+
+				record, err := runner.GetPlanRecord()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(record.Approved).To(BeFalse())
+				Expect(record.ApprovedAt).To(BeNil())
+				Expect(record.PlanHash).NotTo(BeEmpty())
+
+				// an unapproved record does not have an approval time
+				recordJSON, err := os.ReadFile(filepath.Join(cli.WorkingDirectory(), "tf.plan.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(recordJSON)).NotTo(ContainSubstring("approvedAt"))
+				Expect(record.Summary.String()).To(Equal(summary.String()))
+
+				_, err = runner.Apply(args)
+				Expect(err).To(Equal(terraform.ErrPlanNotApproved))
+
+				_, err = runner.ApprovePlan(map[string]string{"test_input": "arg value 2"}, "reviewer")
+				Expect(err).To(Equal(terraform.ErrPlanInputsChanged))
+
+				record, err = runner.ApprovePlan(args, "reviewer")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(record.Approved).To(BeTrue())
+				Expect(record.ApprovedBy).To(Equal("reviewer"))
+				Expect(record.ApprovedAt).NotTo(BeNil())
+
+				record, err = runner.GetPlanRecord()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(record.ApprovedAt).NotTo(BeNil())
+
+				cli.ExpectFakeRequest(applyRequestKey)
 				cli.ExpectFakeRequest(outputRequestKey)
+				_, err = runner.Apply(args)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+
+				_, err = runner.GetPlanRecord()
+				Expect(err).To(Equal(terraform.ErrNoPlan))
+			})
+
+			It("does not approve a plan whose environment variable inputs have changed", func() {
+
+				cli.ExpectFakeRequest(planRequestKey)
+				cli.ExpectFakeRequest(showPlanRequestKey)
+				_, err = runner.Plan(args)
+				Expect(err).NotTo(HaveOccurred())
+
+				// variables passed via the environment are plan inputs
+				runner.AddToEnv(map[string]string{"TF_VAR_test_input_2": "env value 2"})
+				_, err = runner.ApprovePlan(args, "reviewer")
+				Expect(err).To(Equal(terraform.ErrPlanInputsChanged))
 
+				// other environment variables are not
 				runner.SetEnv(
 					map[string]string{
 						"envvar1": "envvar value 1",
 						"envvar2": "envvar value 2",
+						"envvar3": "envvar value 3",
 					},
 				)
-				output, err = runner.GetOutput()
+				record, err := runner.ApprovePlan(args, "reviewer")
 				Expect(err).NotTo(HaveOccurred())
-				Expect(output).NotTo(BeNil())
-				Expect(len(output)).To(Equal(2))
-				Expect(output["output1"]).To(Equal(terraform.Output{
-					Sensitive: false,
-					Type:      "string",
-					Value:     "output value 1",
-				}))
-				Expect(output["output2"]).To(Equal(terraform.Output{
-					Sensitive: false,
-					Type:      "number",
-					Value:     "2",
-				}))
+				Expect(record.Approved).To(BeTrue())
 			})
 
-			It("handles 'terraform output' failure", func() {
+			It("does not approve a plan that has expired or changed", func() {
+
+				cli.ExpectFakeRequest(planRequestKey)
+				cli.ExpectFakeRequest(showPlanRequestKey)
+				_, err = runner.Plan(args)
+				Expect(err).NotTo(HaveOccurred())
+
+				runner.SetPlanExpiry(time.Nanosecond)
+				time.Sleep(time.Millisecond)
+				_, err = runner.ApprovePlan(args, "reviewer")
+				Expect(err).To(Equal(terraform.ErrPlanExpired))
+
+				runner.SetPlanExpiry(0)
+				err = os.WriteFile(planPath, []byte("another plan"), 0600)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = runner.ApprovePlan(args, "reviewer")
+				Expect(err).To(Equal(terraform.ErrPlanChanged))
+			})
+
+			It("creates a new plan if the saved plan's inputs have changed", func() {
+
+				cli.ExpectFakeRequest(planRequestKey)
+				cli.ExpectFakeRequest(showPlanRequestKey)
+				_, err = runner.Plan(args)
+				Expect(err).NotTo(HaveOccurred())
+
+				cli.ExpectFakeRequest(planRequestKey)
+				cli.ExpectFakeRequest(applyRequestKey)
+				cli.ExpectFakeRequest(outputRequestKey)
+				_, err = runner.Apply(map[string]string{"test_input": "arg value 2"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+			})
+		})
 
+		Context("targets", func() {
+
+			BeforeEach(func() {
+				runner.SetEnv(
+					map[string]string{
+						"envvar1": "envvar value 1",
+						"envvar2": "envvar value 2",
+					},
+				)
 				cli.ExpectFakeRequest(cli.AddFakeResponse(
 					[]string{
-						"output",
-						"-json",
+						"state",
+						"list",
 					},
-					[]string{},
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"aws_instance.bastion\naws_eip.bastion[0]\naws_eip.bastion[1]\nmodule.vpc.aws_vpc.main\n",
 					"",
-					"Error: getting output",
-					fmt.Errorf("Error!"),
+					nil,
 				))
+			})
 
-				output, err = runner.GetOutput()
+			It("validates target addresses against the state", func() {
+
+				err = runner.ValidateTargets([]string{"aws_instance.bastion", "aws_eip.bastion", "module.vpc"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+			})
+
+			It("returns an error for target addresses not in the state", func() {
+
+				err = runner.ValidateTargets([]string{"aws_instance.bastion", "aws_instance.vpn", "module.vp"})
 				Expect(err).To(HaveOccurred())
-				Expect(output).To(BeNil())
-				Expect(errorBuffer.String()).To(Equal("Error: getting output"))
+				Expect(err.Error()).To(Equal("the following target resources were not found in the state: aws_instance.vpn,module.vp"))
 			})
-		})
 
-		Context("destroy", func() {
+			It("plans and destroys only the targeted resources", func() {
 
-			It("executes 'terraform destroy'", func() {
+				err = runner.ValidateTargets([]string{"aws_instance.bastion", "module.vpc"})
+				Expect(err).NotTo(HaveOccurred())
+				runner.SetTargets([]string{"aws_instance.bastion", "module.vpc"})
 
 				cli.ExpectFakeRequest(cli.AddFakeResponse(
 					[]string{
 						"-chdir=" + testRecipePath,
-						"apply",
-						"-destroy",
-						"-auto-approve",
+						"plan",
+						"-input=false",
+						"-out=/goutils/test/cli/workingdirectory/tf.plan",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+						"-target=aws_instance.bastion",
+						"-target=module.vpc",
 					},
 					[]string{
 						"envvar1=envvar value 1",
 						"envvar2=envvar value 2",
 					},
-					"Destroy complete! Resources: 1 destroyed.",
+					"Plan: 0 to add, 2 to change, 0 to destroy.",
 					"",
 					nil,
 				))
+				cli.ExpectFakeRequest(showPlanRequestKey)
 
-				runner.SetEnv(
+				_, err = runner.Plan(
 					map[string]string{
-						"envvar1": "envvar value 1",
-						"envvar2": "envvar value 2",
+						"test_input": "arg value 1",
 					},
 				)
-				err = runner.Destroy()
 				Expect(err).NotTo(HaveOccurred())
-				Expect(outputBuffer.String()).To(HavePrefix("Destroy complete! Resources: 1 destroyed."))
-			})
-
-			It("handles 'terraform destroy' failure", func() {
 
 				cli.ExpectFakeRequest(cli.AddFakeResponse(
 					[]string{
@@ -401,83 +734,910 @@ var _ = Describe("Runner", func() {
 						"apply",
 						"-destroy",
 						"-auto-approve",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+						"-target=aws_instance.bastion",
+						"-target=module.vpc",
 					},
 					[]string{
 						"envvar1=envvar value 1",
 						"envvar2=envvar value 2",
 					},
+					"Destroy complete! Resources: 2 destroyed.",
 					"",
-					"Error: Error destroying resources",
-					fmt.Errorf("Error!"),
+					nil,
 				))
 
+				err = runner.Destroy(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+			})
+		})
+
+		Context("state", func() {
+
+			var (
+				showStateRequestKey string
+			)
+
+			BeforeEach(func() {
 				runner.SetEnv(
 					map[string]string{
 						"envvar1": "envvar value 1",
 						"envvar2": "envvar value 2",
 					},
 				)
-				err = runner.Destroy()
-				Expect(err).To(HaveOccurred())
-				Expect(errorBuffer.String()).To(Equal("Error: Error destroying resources"))
+				showStateRequestKey = cli.AddFakeResponse(
+					[]string{
+						"show",
+						"-json",
+					},
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					stateJSON,
+					"",
+					nil,
+				)
+				cli.ExpectFakeRequest(showStateRequestKey)
 			})
-		})
-	})
 
-	Describe("terraform configuration variables", func() {
+			It("lists the resources in the state including those of child modules", func() {
 
-		BeforeEach(func() {
-			outputBuffer.Reset()
-			errorBuffer.Reset()
-			cli.Reset()
+				resources, err := runner.StateList()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(resources)).To(Equal(3))
+
+				Expect(resources[0].Address).To(Equal("aws_instance.bastion"))
+				Expect(resources[0].ModuleAddress).To(Equal(""))
+				Expect(resources[0].Mode).To(Equal("managed"))
+				Expect(resources[0].Type).To(Equal("aws_instance"))
+				Expect(resources[0].Name).To(Equal("bastion"))
+				Expect(resources[0].ProviderName).To(Equal("registry.terraform.io/hashicorp/aws"))
+				Expect(resources[0].ID).To(Equal("i-0a1b2c3d"))
+				Expect(resources[0].Tainted).To(BeTrue())
+				Expect(resources[0].Attributes["instance_type"]).To(Equal("t3.micro"))
+				Expect(resources[0].IsSensitive("user_data")).To(BeTrue())
+				Expect(resources[0].IsSensitive("instance_type")).To(BeFalse())
+
+				Expect(resources[1].Address).To(Equal("aws_eip.bastion[0]"))
+				Expect(resources[1].Index).To(Equal(float64(0)))
+				Expect(resources[1].DependsOn).To(Equal([]string{"aws_instance.bastion"}))
+
+				Expect(resources[2].Address).To(Equal("module.vpc.aws_vpc.main"))
+				Expect(resources[2].ModuleAddress).To(Equal("module.vpc"))
+				Expect(resources[2].ID).To(Equal("vpc-1234"))
+			})
 
-			testRecipePath = "x/y/z/configpath"
-			runner = terraform.NewRunner(cli,
-				testRecipePath,
-				testPluginPath,
-				map[string]terraform.Input{
-					"test_input_1": {false},
-					"test_input_2": {false},
-					"test_input_3": {true},
-					"test_input_5": {false},
-					"test_input_7": {true},
-				})
+			It("shows a resource in the state", func() {
+
+				resource, err := runner.StateShow("module.vpc.aws_vpc.main")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resource.Attributes["cidr_block"]).To(Equal("10.0.0.0/16"))
+
+				cli.ExpectFakeRequest(showStateRequestKey)
+				_, err = runner.StateShow("aws_instance.vpn")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("resource 'aws_instance.vpn' was not found in the state"))
+			})
+
+			It("reads the outputs in the state", func() {
+
+				state, err := runner.GetState()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(state.TerraformVersion).To(Equal("1.5.7"))
+				Expect(state.Outputs["bastion_ip"].Value).To(Equal("1.2.3.4"))
+				Expect(state.Resource("aws_instance.bastion")).NotTo(BeNil())
+			})
 		})
 
-		Context("runtime", func() {
+		Context("state surgery", func() {
 
-			It("succeeds if required arguments are provided when calling apply", func() {
+			var (
+				backupDir,
+				statePullRequestKey string
+			)
 
-				cli.ExpectFakeRequest(cli.AddFakeResponse(
+			BeforeEach(func() {
+				backupDir = filepath.Join(cli.WorkingDirectory(), "state-backups")
+
+				runner.SetEnv(
+					map[string]string{
+						"envvar1": "envvar value 1",
+						"envvar2": "envvar value 2",
+					},
+				)
+				statePullRequestKey = cli.AddFakeResponse(
 					[]string{
-						"-chdir=" + testRecipePath,
-						"plan",
-						"-input=false",
-						"-out=/goutils/test/cli/workingdirectory/tf.plan",
-						"-var", "test_input_1=abcd1",
-						"-var", "test_input_2=abcd2",
-						"-var", "test_input_3=abcd3",
-						"-var", "test_input_5=abcd5",
-						"-var", "test_input_7=abcd7",
+						"state",
+						"pull",
 					},
-					[]string{},
-					"",
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					`{"version": 4, "serial": 3}`,
 					"",
 					nil,
-				))
+				)
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(backupDir)
+			})
+
+			expectStateBackups := func(n int) {
+				backups, err := os.ReadDir(backupDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(backups)).To(Equal(n))
+
+				for _, backup := range backups {
+					info, err := backup.Info()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+					state, err := os.ReadFile(filepath.Join(backupDir, backup.Name()))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(state)).To(Equal(`{"version": 4, "serial": 3}`))
+				}
+			}
+
+			It("backs up the state and imports a resource", func() {
+
+				cli.ExpectFakeRequest(statePullRequestKey)
 				cli.ExpectFakeRequest(cli.AddFakeResponse(
 					[]string{
 						"-chdir=" + testRecipePath,
-						"apply",
-						"/goutils/test/cli/workingdirectory/tf.plan",
+						"import",
+						"-input=false",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+						"aws_instance.bastion",
+						"i-0a1b2c3d",
 					},
-					[]string{},
-					"",
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"Import successful!",
 					"",
 					nil,
 				))
-				cli.ExpectFakeRequest(cli.AddFakeResponse(
-					[]string{
+
+				err = runner.Import(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+					"aws_instance.bastion", "i-0a1b2c3d",
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+				Expect(outputBuffer.String()).To(Equal("Import successful!"))
+				expectStateBackups(1)
+			})
+
+			It("backs up the state and moves and removes resources", func() {
+
+				cli.ExpectFakeRequest(statePullRequestKey)
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"state",
+						"mv",
+						"aws_instance.bastion",
+						"module.bastion.aws_instance.node",
+					},
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"Successfully moved 1 object(s).",
+					"",
+					nil,
+				))
+				err = runner.StateMove("aws_instance.bastion", "module.bastion.aws_instance.node")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+
+				cli.ExpectFakeRequest(statePullRequestKey)
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"state",
+						"rm",
+						"aws_eip.bastion[0]",
+						"aws_eip.bastion[1]",
+					},
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"Successfully removed 2 resource instance(s).",
+					"",
+					nil,
+				))
+				err = runner.StateRemove([]string{"aws_eip.bastion[0]", "aws_eip.bastion[1]"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+				expectStateBackups(2)
+			})
+
+			It("does not change the state if it cannot be backed up", func() {
+
+				cli.Reset()
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"state",
+						"pull",
+					},
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"",
+					"Error: failed to read state",
+					fmt.Errorf("exit status 1"),
+				))
+
+				err = runner.StateRemove([]string{"aws_eip.bastion[0]"})
+				Expect(err).To(HaveOccurred())
+				_, err = os.Stat(backupDir)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		Context("drift", func() {
+
+			var (
+				driftPlanArgs []string
+			)
+
+			BeforeEach(func() {
+				runner.SetEnv(
+					map[string]string{
+						"envvar1": "envvar value 1",
+						"envvar2": "envvar value 2",
+					},
+				)
+				driftPlanArgs = []string{
+					"-chdir=" + testRecipePath,
+					"plan",
+					"-refresh-only",
+					"-detailed-exitcode",
+					"-input=false",
+					"-out=/goutils/test/cli/workingdirectory/tf-drift.plan",
+					"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+				}
+			})
+
+			It("reports no drift when the refresh-only plan has no changes", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					driftPlanArgs,
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"No changes. Your infrastructure still matches the configuration.",
+					"",
+					nil,
+				))
+
+				report, err := runner.DetectDrift(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+				Expect(report.HasDrift()).To(BeFalse())
+			})
+
+			It("reports the attribute changes of resources that have drifted", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					driftPlanArgs,
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"Terraform detected the following changes made outside of Terraform",
+					"",
+					exitError{code: 2},
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"show",
+						"-json",
+						"/goutils/test/cli/workingdirectory/tf-drift.plan",
+					},
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					driftPlanJSON,
+					"",
+					nil,
+				))
+
+				report, err := runner.DetectDrift(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+				Expect(report.HasDrift()).To(BeTrue())
+				Expect(report.String()).To(Equal("Drift: 2 resources changed outside of terraform."))
+
+				bastion := report.Resource("aws_instance.bastion")
+				Expect(bastion).NotTo(BeNil())
+				Expect(bastion.Action).To(Equal(terraform.ActionUpdate))
+				Expect(len(bastion.Attributes)).To(Equal(3))
+				Expect(*bastion.Attributes[0]).To(Equal(terraform.AttributeDiff{
+					Path:   "instance_type",
+					Before: "t3.micro",
+					After:  "t3.large",
+				}))
+				Expect(*bastion.Attributes[1]).To(Equal(terraform.AttributeDiff{
+					Path:   "tags.Owner",
+					Before: nil,
+					After:  "ops",
+				}))
+				Expect(*bastion.Attributes[2]).To(Equal(terraform.AttributeDiff{
+					Path:      "user_data",
+					Sensitive: true,
+				}))
+
+				eip := report.Resource("aws_eip.bastion[0]")
+				Expect(eip).NotTo(BeNil())
+				Expect(eip.Action).To(Equal(terraform.ActionDelete))
+				Expect(len(eip.Attributes)).To(Equal(0))
+			})
+
+			It("returns an error when the refresh-only plan fails", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					driftPlanArgs,
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"",
+					"Error!",
+					exitError{code: 1},
+				))
+
+				_, err = runner.DetectDrift(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).To(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+			})
+		})
+
+		Context("state lock", func() {
+
+			var (
+				errorCapture *terraform.ErrorCapture
+			)
+
+			BeforeEach(func() {
+				errorCapture = terraform.NewErrorCapture(&errorBuffer)
+				cli = utils_mocks.NewFakeCLI(&outputBuffer, errorCapture)
+
+				runner = terraform.NewRunner(cli,
+					testRecipePath,
+					testPluginPath,
+					map[string]terraform.Input{
+						"test_input": {Optional: false},
+					})
+				runner.SetErrorCapture(errorCapture)
+			})
+
+			It("returns a state lock error when the state is locked", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"-destroy",
+						"-auto-approve",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+					},
+					[]string{},
+					"",
+					stateLockError,
+					fmt.Errorf("exit status 1"),
+				))
+
+				err = runner.Destroy(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).To(HaveOccurred())
+				Expect(errorBuffer.String()).To(Equal(stateLockError))
+
+				lockErr, ok := err.(*terraform.StateLockError)
+				Expect(ok).To(BeTrue())
+				Expect(lockErr.ID).To(Equal("0a3a4bd4-7f0b-4d5e-9a2c-1f1c0ba4e0a1"))
+				Expect(lockErr.Path).To(Equal("mycs-state/bastion/terraform.tfstate"))
+				Expect(lockErr.Operation).To(Equal("OperationTypeApply"))
+				Expect(lockErr.Who).To(Equal("ops@host1"))
+				Expect(lockErr.Version).To(Equal("1.5.7"))
+				Expect(lockErr.Created.UTC().Format(time.RFC3339)).To(Equal("2023-08-01T12:30:00Z"))
+				Expect(errors.Unwrap(lockErr).Error()).To(Equal("exit status 1"))
+				Expect(err.Error()).To(Equal(
+					"terraform state is locked by 'ops@host1' for operation 'OperationTypeApply' since 2023-08-01T12:30:00Z (lock id: 0a3a4bd4-7f0b-4d5e-9a2c-1f1c0ba4e0a1)"))
+			})
+
+			It("returns the cli error when the failure is not a state lock error", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"-destroy",
+						"-auto-approve",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+					},
+					[]string{},
+					"",
+					"Error: Invalid provider configuration",
+					fmt.Errorf("exit status 1"),
+				))
+
+				err = runner.Destroy(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).To(HaveOccurred())
+				_, ok := err.(*terraform.StateLockError)
+				Expect(ok).To(BeFalse())
+			})
+
+			It("force unlocks the state", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"force-unlock",
+						"-force",
+						"0a3a4bd4-7f0b-4d5e-9a2c-1f1c0ba4e0a1",
+					},
+					[]string{},
+					"Terraform state has been successfully unlocked!",
+					"",
+					nil,
+				))
+
+				err = runner.ForceUnlock("0a3a4bd4-7f0b-4d5e-9a2c-1f1c0ba4e0a1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+
+				err = runner.ForceUnlock("")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("cancellation", func() {
+
+			It("does not run terraform when the context has been cancelled", func() {
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				output, err = runner.ApplyWithContext(ctx,
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).To(Equal(terraform.ErrCancelled))
+				Expect(output).To(BeNil())
+				Expect(outputBuffer.String()).To(Equal(""))
+
+				err = runner.DestroyWithContext(ctx,
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).To(Equal(terraform.ErrCancelled))
+				Expect(outputBuffer.String()).To(Equal(""))
+			})
+		})
+
+		Context("interrupts", func() {
+
+			var (
+				fakeTerraformDir string
+
+				cliOutput,
+				processOutput *gbytes.Buffer
+			)
+
+			// creates a runner for a fake terraform
+			// executable that runs the given script
+			newProcessRunner := func(script string) *terraform.Runner {
+
+				fakeTerraformPath := filepath.Join(fakeTerraformDir, "terraform")
+				err = os.WriteFile(fakeTerraformPath, []byte("#!/bin/sh\n"+script), 0755)
+				Expect(err).NotTo(HaveOccurred())
+
+				cliOutput = gbytes.NewBuffer()
+				processOutput = gbytes.NewBuffer()
+				processCLI, err := run.NewCLI(fakeTerraformPath, fakeTerraformDir, cliOutput, cliOutput)
+				Expect(err).NotTo(HaveOccurred())
+
+				r := terraform.NewRunner(processCLI, testRecipePath, "", map[string]terraform.Input{})
+				r.SetOutput(processOutput, processOutput)
+				r.SetCancelGracePeriod(500 * time.Millisecond)
+				return r
+			}
+
+			// runs init with the given runner and cancels
+			// it once terraform has started
+			cancelInit := func(r *terraform.Runner) (time.Duration, error) {
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				result := make(chan error, 1)
+				go func() {
+					result <- r.InitWithContext(ctx)
+				}()
+				Eventually(processOutput, "5s").Should(gbytes.Say("started"))
+
+				cancelled := time.Now()
+				cancel()
+
+				var err error
+				Eventually(result, "5s").Should(Receive(&err))
+				return time.Since(cancelled), err
+			}
+
+			BeforeEach(func() {
+				fakeTerraformDir, err = os.MkdirTemp("", "fake-terraform")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(fakeTerraformDir)
+			})
+
+			It("interrupts terraform when the context is cancelled", func() {
+
+				r := newProcessRunner(`
+trap 'kill $pid; echo "interrupted"; exit 1' INT
+sleep 10 &
+pid=$!
+echo "started"
+wait $pid
+`)
+				elapsed, err := cancelInit(r)
+				Expect(err).To(Equal(terraform.ErrCancelled))
+				Expect(processOutput).To(gbytes.Say("interrupted"))
+				Expect(elapsed).To(BeNumerically("<", 500*time.Millisecond))
+			})
+
+			It("kills terraform if it does not stop within the grace period after being interrupted", func() {
+
+				r := newProcessRunner(`
+trap 'echo "ignoring interrupt"' INT
+sleep 10 &
+pid=$!
+echo "started"
+wait $pid
+wait $pid
+echo "completed"
+`)
+				elapsed, err := cancelInit(r)
+				Expect(err).To(Equal(terraform.ErrCancelled))
+				Expect(processOutput).To(gbytes.Say("ignoring interrupt"))
+				Expect(processOutput).NotTo(gbytes.Say("completed"))
+				Expect(elapsed).To(BeNumerically(">=", 500*time.Millisecond))
+				Expect(elapsed).To(BeNumerically("<", 5*time.Second))
+			})
+
+			It("does not write the outputs of an apply run as a process", func() {
+
+				r := newProcessRunner(`
+for arg in "$@"; do
+  case "$arg" in
+  plan) echo "Plan: 1 to add, 0 to change, 0 to destroy."; exit 0;;
+  apply) printf 'Apply complete! Resources: 1 added.\n\nOutputs:\n\npassword = "secret"\n'; exit 0;;
+  output) echo '{}'; exit 0;;
+  esac
+done
+`)
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				output, err = r.ApplyWithContext(ctx, map[string]string{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output).To(BeEmpty())
+				Expect(string(processOutput.Contents())).To(ContainSubstring("Apply complete!"))
+				Expect(string(processOutput.Contents())).NotTo(ContainSubstring("secret"))
+			})
+
+			It("runs operations without a cancellable context via the cli", func() {
+
+				r := newProcessRunner(`echo "initialized"`)
+
+				err = r.Init()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cliOutput).To(gbytes.Say("initialized"))
+				Expect(processOutput.Contents()).To(BeEmpty())
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				err = r.InitWithContext(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(processOutput).To(gbytes.Say("initialized"))
+			})
+		})
+
+		Context("events", func() {
+
+			It("streams json ui events of an apply to the event handler", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"plan",
+						"-input=false",
+						"-out=/goutils/test/cli/workingdirectory/tf.plan",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+						"-json",
+					},
+					[]string{},
+					`{"@level":"info","@message":"Terraform 1.5.0","@module":"terraform.ui","@timestamp":"2023-06-14T10:00:00.000000-04:00","terraform":"1.5.0","type":"version","ui":"1.1"}
+{"@level":"info","@message":"aws_instance.bastion: Plan to create","@module":"terraform.ui","@timestamp":"2023-06-14T10:00:01.000000-04:00","change":{"resource":{"addr":"aws_instance.bastion","module":"","resource":"aws_instance.bastion","implied_provider":"aws","resource_type":"aws_instance","resource_name":"bastion","resource_key":null},"action":"create"},"type":"planned_change"}
+{"@level":"info","@message":"Plan: 1 to add, 0 to change, 0 to destroy.","@module":"terraform.ui","@timestamp":"2023-06-14T10:00:01.000000-04:00","changes":{"add":1,"change":0,"remove":0,"operation":"plan"},"type":"change_summary"}
+`,
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"-json",
+						"/goutils/test/cli/workingdirectory/tf.plan",
+					},
+					[]string{},
+					`{"@level":"info","@message":"aws_instance.bastion: Creating...","@module":"terraform.ui","@timestamp":"2023-06-14T10:00:02.000000-04:00","hook":{"resource":{"addr":"aws_instance.bastion","module":"","resource":"aws_instance.bastion","implied_provider":"aws","resource_type":"aws_instance","resource_name":"bastion","resource_key":null},"action":"create"},"type":"apply_start"}
+{"@level":"warn","@message":"Warning: Deprecated attribute","@module":"terraform.ui","@timestamp":"2023-06-14T10:00:03.000000-04:00","diagnostic":{"severity":"warning","summary":"Deprecated attribute","detail":"The attribute is deprecated.","range":{"filename":"main.tf","start":{"line":12,"column":3,"byte":200},"end":{"line":12,"column":20,"byte":217}}},"type":"diagnostic"}
+{"@level":"info","@message":"aws_instance.bastion: Creation complete after 5s [id=i-1234]","@module":"terraform.ui","@timestamp":"2023-06-14T10:00:07.000000-04:00","hook":{"resource":{"addr":"aws_instance.bastion","module":"","resource":"aws_instance.bastion","implied_provider":"aws","resource_type":"aws_instance","resource_name":"bastion","resource_key":null},"action":"create","id_key":"id","id_value":"i-1234","elapsed_seconds":5},"type":"apply_complete"}
+{"@level":"info","@message":"Apply complete! Resources: 1 added, 0 changed, 0 destroyed.","@module":"terraform.ui","@timestamp":"2023-06-14T10:00:07.000000-04:00","changes":{"add":1,"change":0,"remove":0,"operation":"apply"},"type":"change_summary"}
+{"@level":"info","@message":"Outputs: 1","@module":"terraform.ui","@timestamp":"2023-06-14T10:00:07.000000-04:00","outputs":{"output1":{"sensitive":false,"type":"string","value":"output value 1"}},"type":"outputs"}
+`,
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"output",
+						"-json",
+					},
+					[]string{},
+					`{}`,
+					"",
+					nil,
+				))
+
+				events := []*terraform.Event{}
+				runner.SetEventHandler(func(event *terraform.Event) {
+					events = append(events, event)
+				})
+
+				output, err = runner.Apply(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+				Expect(outputBuffer.String()).To(Equal(""))
+
+				Expect(len(events)).To(Equal(8))
+				Expect(events[0].Type).To(Equal(terraform.EventVersion))
+				Expect(events[1].Type).To(Equal(terraform.EventPlannedChange))
+				Expect(events[1].Change.Resource.Addr).To(Equal("aws_instance.bastion"))
+				Expect(events[1].Change.Action).To(Equal("create"))
+				Expect(events[2].Type).To(Equal(terraform.EventChangeSummary))
+				Expect(*events[2].Changes).To(Equal(terraform.ChangeCounts{Add: 1, Operation: "plan"}))
+				Expect(events[3].Type).To(Equal(terraform.EventApplyStart))
+				Expect(events[3].Hook.Resource.Addr).To(Equal("aws_instance.bastion"))
+				Expect(events[4].Type).To(Equal(terraform.EventDiagnostic))
+				Expect(events[4].Diagnostic.Severity).To(Equal("warning"))
+				Expect(events[4].Diagnostic.Range.Filename).To(Equal("main.tf"))
+				Expect(events[4].Diagnostic.Range.Start.Line).To(Equal(12))
+				Expect(events[5].Type).To(Equal(terraform.EventApplyComplete))
+				Expect(events[5].Hook.IDValue).To(Equal("i-1234"))
+				Expect(events[5].Hook.ElapsedSeconds).To(Equal(float64(5)))
+				Expect(events[6].Type).To(Equal(terraform.EventChangeSummary))
+				Expect(events[6].Changes.Operation).To(Equal("apply"))
+				Expect(events[7].Type).To(Equal(terraform.EventOutputs))
+				Expect(events[7].Outputs["output1"].Value).To(Equal("output value 1"))
+			})
+		})
+
+		Context("output", func() {
+
+			It("executes 'terraform output' and reads the output", func() {
+
+				cli.ExpectFakeRequest(outputRequestKey)
+
+				runner.SetEnv(
+					map[string]string{
+						"envvar1": "envvar value 1",
+						"envvar2": "envvar value 2",
+					},
+				)
+				output, err = runner.GetOutput()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output).NotTo(BeNil())
+				Expect(len(output)).To(Equal(2))
+				Expect(output["output1"]).To(Equal(terraform.Output{
+					Sensitive: false,
+					Type:      "string",
+					Value:     "output value 1",
+				}))
+				Expect(output["output2"]).To(Equal(terraform.Output{
+					Sensitive: false,
+					Type:      "number",
+					Value:     "2",
+				}))
+			})
+
+			It("handles 'terraform output' failure", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"output",
+						"-json",
+					},
+					[]string{},
+					"",
+					"Error: getting output",
+					fmt.Errorf("Error!"),
+				))
+
+				output, err = runner.GetOutput()
+				Expect(err).To(HaveOccurred())
+				Expect(output).To(BeNil())
+				Expect(errorBuffer.String()).To(Equal("Error: getting output"))
+			})
+		})
+
+		Context("destroy", func() {
+
+			It("executes 'terraform destroy'", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"-destroy",
+						"-auto-approve",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+					},
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"Destroy complete! Resources: 1 destroyed.",
+					"",
+					nil,
+				))
+
+				runner.SetEnv(
+					map[string]string{
+						"envvar1": "envvar value 1",
+						"envvar2": "envvar value 2",
+					},
+				)
+				err = runner.Destroy(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(outputBuffer.String()).To(HavePrefix("Destroy complete! Resources: 1 destroyed."))
+			})
+
+			It("handles 'terraform destroy' failure", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"-destroy",
+						"-auto-approve",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+					},
+					[]string{
+						"envvar1=envvar value 1",
+						"envvar2=envvar value 2",
+					},
+					"",
+					"Error: Error destroying resources",
+					fmt.Errorf("Error!"),
+				))
+
+				runner.SetEnv(
+					map[string]string{
+						"envvar1": "envvar value 1",
+						"envvar2": "envvar value 2",
+					},
+				)
+				err = runner.Destroy(
+					map[string]string{
+						"test_input": "arg value 1",
+					},
+				)
+				Expect(err).To(HaveOccurred())
+				Expect(errorBuffer.String()).To(Equal("Error: Error destroying resources"))
+			})
+		})
+	})
+
+	Describe("terraform configuration variables", func() {
+
+		BeforeEach(func() {
+			outputBuffer.Reset()
+			errorBuffer.Reset()
+			cli.Reset()
+
+			testRecipePath = "x/y/z/configpath"
+			runner = terraform.NewRunner(cli,
+				testRecipePath,
+				testPluginPath,
+				map[string]terraform.Input{
+					"test_input_1": {Optional: false},
+					"test_input_2": {Optional: false},
+					"test_input_3": {Optional: true},
+					"test_input_5": {Optional: false},
+					"test_input_7": {Optional: true},
+				})
+		})
+
+		Context("runtime", func() {
+
+			It("succeeds if required arguments are provided when calling apply", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"plan",
+						"-input=false",
+						"-out=/goutils/test/cli/workingdirectory/tf.plan",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+					},
+					[]string{},
+					"",
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"apply",
+						"/goutils/test/cli/workingdirectory/tf.plan",
+					},
+					[]string{},
+					"",
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
 						"output",
 						"-json",
 					},
@@ -529,6 +1689,302 @@ var _ = Describe("Runner", func() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("the following argument is not known by the templates: test_input_8"))
 			})
+
+			It("passes variables via a json variables file which is removed after the command", func() {
+
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"plan",
+						"-input=false",
+						"-out=/goutils/test/cli/workingdirectory/tf.plan",
+						"-var-file=/goutils/test/cli/workingdirectory/tf.tfvars.json",
+					},
+					[]string{},
+					"",
+					"",
+					nil,
+				))
+				cli.ExpectFakeRequest(cli.AddFakeResponse(
+					[]string{
+						"-chdir=" + testRecipePath,
+						"show",
+						"-json",
+						"/goutils/test/cli/workingdirectory/tf.plan",
+					},
+					[]string{},
+					`{}`,
+					"",
+					nil,
+				))
+
+				_, err = runner.Plan(
+					map[string]string{
+						"test_input_1": "abcd1",
+						"test_input_2": "abcd2",
+						"test_input_5": "abcd5",
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+
+				_, err = os.Stat(filepath.Join(cli.WorkingDirectory(), "tf.tfvars.json"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		Context("types", func() {
+
+			BeforeEach(func() {
+				runner = terraform.NewRunner(cli,
+					testRecipePath,
+					testPluginPath,
+					map[string]terraform.Input{
+						"name":     {Optional: false},
+						"count":    {Optional: false, Type: "number"},
+						"enabled":  {Optional: true, Type: "bool"},
+						"zones":    {Optional: true, Type: "list(string)"},
+						"tags":     {Optional: true, Type: "map(string)"},
+						"anything": {Optional: true, Type: "any"},
+						"untyped":  {Optional: true},
+					})
+			})
+
+			It("preserves the json types of variable values", func() {
+
+				tfVars, err := runner.TFVarsJSON(
+					map[string]string{
+						"name":     "my name",
+						"count":    "3",
+						"enabled":  "true",
+						"zones":    `["us-east-1a", "us-east-1b"]`,
+						"tags":     `{"owner": "ops"}`,
+						"anything": "plain text",
+						"untyped":  "12",
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tfVars).To(MatchJSON(`{
+					"name": "my name",
+					"count": 3,
+					"enabled": true,
+					"zones": ["us-east-1a", "us-east-1b"],
+					"tags": {"owner": "ops"},
+					"anything": "plain text",
+					"untyped": "12"
+				}`))
+			})
+
+			It("returns an error with the validation's message if a value is not valid", func() {
+
+				validation, err := terraform.NewVariableValidation(
+					"count",
+					"var.count >= 1 && var.count <= 5",
+					"The count must be between 1 and 5.",
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				runner = terraform.NewRunner(cli,
+					testRecipePath,
+					testPluginPath,
+					map[string]terraform.Input{
+						"count": {Optional: false, Type: "number", Validations: []*terraform.VariableValidation{validation}},
+					})
+
+				_, err = runner.Plan(map[string]string{"count": "8"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("invalid value for argument 'count': The count must be between 1 and 5."))
+				Expect(cli.IsExpectedRequestStackEmpty()).To(BeTrue())
+			})
+
+			It("returns an error if a value cannot be converted to its type", func() {
+
+				_, err = runner.TFVarsJSON(map[string]string{"count": "three"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("invalid value for argument 'count': 'three' is not a number"))
+
+				_, err = runner.TFVarsJSON(map[string]string{"zones": "us-east-1a"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("invalid value for argument 'zones': value is not a json encoded list(string)"))
+			})
 		})
 	})
 })
+
+const stateJSON = `{
+  "format_version": "1.0",
+  "terraform_version": "1.5.7",
+  "values": {
+    "outputs": {
+      "bastion_ip": {
+        "sensitive": false,
+        "value": "1.2.3.4",
+        "type": "string"
+      }
+    },
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_instance.bastion",
+          "mode": "managed",
+          "type": "aws_instance",
+          "name": "bastion",
+          "provider_name": "registry.terraform.io/hashicorp/aws",
+          "schema_version": 1,
+          "values": {
+            "id": "i-0a1b2c3d",
+            "instance_type": "t3.micro",
+            "user_data": "secret"
+          },
+          "sensitive_values": {
+            "user_data": true
+          },
+          "tainted": true
+        },
+        {
+          "address": "aws_eip.bastion[0]",
+          "mode": "managed",
+          "type": "aws_eip",
+          "name": "bastion",
+          "index": 0,
+          "provider_name": "registry.terraform.io/hashicorp/aws",
+          "schema_version": 0,
+          "values": {
+            "id": "eipalloc-1234"
+          },
+          "sensitive_values": {},
+          "depends_on": [
+            "aws_instance.bastion"
+          ]
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.vpc",
+          "resources": [
+            {
+              "address": "module.vpc.aws_vpc.main",
+              "mode": "managed",
+              "type": "aws_vpc",
+              "name": "main",
+              "provider_name": "registry.terraform.io/hashicorp/aws",
+              "schema_version": 1,
+              "values": {
+                "id": "vpc-1234",
+                "cidr_block": "10.0.0.0/16"
+              },
+              "sensitive_values": {}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+// cli error with the exit code of the failed command
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e exitError) ExitCode() int {
+	return e.code
+}
+
+const driftPlanJSON = `{
+  "format_version": "1.2",
+  "terraform_version": "1.5.7",
+  "resource_drift": [
+    {
+      "address": "aws_instance.bastion",
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "bastion",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["update"],
+        "before": {
+          "id": "i-0a1b2c3d",
+          "instance_type": "t3.micro",
+          "tags": { "Name": "bastion" },
+          "user_data": "secret1"
+        },
+        "after": {
+          "id": "i-0a1b2c3d",
+          "instance_type": "t3.large",
+          "tags": { "Name": "bastion", "Owner": "ops" },
+          "user_data": "secret2"
+        },
+        "before_sensitive": { "tags": {}, "user_data": true },
+        "after_sensitive": { "tags": {}, "user_data": true }
+      }
+    },
+    {
+      "address": "aws_eip.bastion[0]",
+      "mode": "managed",
+      "type": "aws_eip",
+      "name": "bastion",
+      "index": 0,
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["delete"],
+        "before": { "id": "eipalloc-1234" },
+        "after": null,
+        "before_sensitive": {},
+        "after_sensitive": false
+      }
+    }
+  ],
+  "resource_changes": []
+}`
+
+// error output of terraform 1.5.7 when the state is locked
+const stateLockError = `╷
+│ Error: Error acquiring the state lock
+│ 
+│ Error message: ConditionalCheckFailedException: The conditional request
+│ failed
+│ Lock Info:
+│   ID:        0a3a4bd4-7f0b-4d5e-9a2c-1f1c0ba4e0a1
+│   Path:      mycs-state/bastion/terraform.tfstate
+│   Operation: OperationTypeApply
+│   Who:       ops@host1
+│   Version:   1.5.7
+│   Created:   2023-08-01 12:30:00.123456789 +0000 UTC
+│   Info:      
+│ 
+│ 
+│ Terraform acquires a state lock to protect the state from being written
+│ by multiple users at the same time. Please resolve the issue above and try
+│ again. For most commands, you can disable locking with the "-lock=false"
+│ flag, but this is not recommended.
+╵
+`
+
+const validateJSON = `{
+  "format_version": "1.0",
+  "valid": false,
+  "error_count": 1,
+  "warning_count": 1,
+  "diagnostics": [
+    {
+      "severity": "error",
+      "summary": "Unsupported block type",
+      "detail": "Blocks of type \"resorce\" are not expected here.",
+      "range": {
+        "filename": "main.tf",
+        "start": { "line": 12, "column": 1, "byte": 180 },
+        "end": { "line": 12, "column": 8, "byte": 187 }
+      }
+    },
+    {
+      "severity": "warning",
+      "summary": "Deprecated attribute",
+      "detail": "The attribute \"name\" is deprecated."
+    }
+  ]
+}`
@@ -0,0 +1,256 @@
+package terraform
+
+import (
+	"strings"
+)
+
+/**
+ * JSON Schema of Recipe Input Forms
+ */
+
+// version of the json schema specification
+// recipe input schemas conform to
+const JSONSchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// a json schema describing a recipe's inputs. the
+// schema is extended with 'x-' keywords for recipe
+// metadata that json schema does not describe.
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type    string        `json:"type,omitempty"`
+	Default interface{}   `json:"default,omitempty"`
+	Enum    []interface{} `json:"enum,omitempty"`
+	Pattern string        `json:"pattern,omitempty"`
+	Not     *JSONSchema   `json:"not,omitempty"`
+
+	WriteOnly  bool `json:"writeOnly,omitempty"`
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+
+	// recipe variable metadata
+	TerraformType        string   `json:"x-terraform-type,omitempty"`
+	Sensitive            bool     `json:"x-sensitive,omitempty"`
+	EnvVars              []string `json:"x-env-vars,omitempty"`
+	DependsOn            []string `json:"x-depends-on,omitempty"`
+	Tags                 []string `json:"x-tags,omitempty"`
+	TargetKey            bool     `json:"x-target-key,omitempty"`
+	Group                string   `json:"x-group,omitempty"`
+	VisibleIf            string   `json:"x-visible-if,omitempty"`
+	RequiredIf           string   `json:"x-required-if,omitempty"`
+	ValueInclusionFilter string   `json:"x-value-inclusion-filter,omitempty"`
+	ValueExclusionFilter string   `json:"x-value-exclusion-filter,omitempty"`
+	RenamedFrom          []string `json:"x-renamed-from,omitempty"`
+	DeprecationMessage   string   `json:"x-deprecation-message,omitempty"`
+
+	// recipe metadata
+	PropertyOrder []string           `json:"x-property-order,omitempty"`
+	Groups        []*JSONSchemaGroup `json:"x-groups,omitempty"`
+}
+
+// a group of a recipe's input properties
+type JSONSchemaGroup struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Properties  []string `json:"properties"`
+}
+
+// in: title - the title of the schema
+// in: description - the description of the schema
+// out: a schema of an object with no properties
+func NewInputSchema(title, description string) *JSONSchema {
+	return &JSONSchema{
+		Schema:      JSONSchemaVersion,
+		Title:       title,
+		Description: description,
+
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema),
+		AdditionalProperties: false,
+		Required:             []string{},
+		PropertyOrder:        []string{},
+	}
+}
+
+// adds the schema of an input property
+//
+// in: name - the name of the property
+// in: property - the schema of the property
+// in: required - whether the property is required
+func (s *JSONSchema) AddProperty(name string, property *JSONSchema, required bool) {
+
+	if _, exists := s.Properties[name]; !exists {
+		s.PropertyOrder = append(s.PropertyOrder, name)
+	}
+	s.Properties[name] = property
+	if required {
+		s.Required = append(s.Required, name)
+	}
+}
+
+// out: a deep copy of the schema
+func (s *JSONSchema) Copy() *JSONSchema {
+
+	if s == nil {
+		return nil
+	}
+	copy := *s
+
+	copy.Default = copyValue(s.Default)
+	if s.Enum != nil {
+		copy.Enum = make([]interface{}, len(s.Enum))
+		for i, v := range s.Enum {
+			copy.Enum[i] = copyValue(v)
+		}
+	}
+	copy.Not = s.Not.Copy()
+	copy.Items = s.Items.Copy()
+	if s.Properties != nil {
+		copy.Properties = make(map[string]*JSONSchema, len(s.Properties))
+		for name, property := range s.Properties {
+			copy.Properties[name] = property.Copy()
+		}
+	}
+	if additionalProperties, ok := s.AdditionalProperties.(*JSONSchema); ok {
+		copy.AdditionalProperties = additionalProperties.Copy()
+	}
+	copy.Required = copyStrings(s.Required)
+
+	copy.EnvVars = copyStrings(s.EnvVars)
+	copy.DependsOn = copyStrings(s.DependsOn)
+	copy.Tags = copyStrings(s.Tags)
+	copy.RenamedFrom = copyStrings(s.RenamedFrom)
+
+	copy.PropertyOrder = copyStrings(s.PropertyOrder)
+	if s.Groups != nil {
+		copy.Groups = make([]*JSONSchemaGroup, len(s.Groups))
+		for i, g := range s.Groups {
+			copy.Groups[i] = &JSONSchemaGroup{
+				Name:        g.Name,
+				Description: g.Description,
+				Properties:  copyStrings(g.Properties),
+			}
+		}
+	}
+	return &copy
+}
+
+// out: a deep copy of a json decoded value
+func copyValue(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = copyValue(e)
+		}
+		return values
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			values[k] = copyValue(e)
+		}
+		return values
+	}
+	return value
+}
+
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// out: the json schema of a variable's inputs
+func (vm *variableMetadata) inputSchema() *JSONSchema {
+
+	property := typeSchema(vm.typeName)
+	property.Title = vm.displayName
+	property.Description = vm.description
+	property.TerraformType = vm.typeName
+
+	// defaults of sensitive variables
+	// are not exported with the schema
+	if vm.optional && !vm.sensitive {
+		if value, err := typedValue(vm.typeName, vm.defaultValue); err == nil {
+			property.Default = value
+		}
+	}
+	if len(vm.acceptedValues) > 0 {
+		property.Enum = make([]interface{}, 0, len(vm.acceptedValues))
+		for _, v := range vm.acceptedValues {
+			if value, err := typedValue(vm.typeName, v); err == nil {
+				property.Enum = append(property.Enum, value)
+			}
+		}
+	}
+
+	// filters match the string form of a value
+	// which is the value itself only for strings.
+	// the filters for other types are derived from
+	// the type which the schema's type describes.
+	kind := typeKind(vm.typeName)
+	if kind == "" || kind == "string" {
+		property.Pattern = vm.valueInclusionFilter
+		if len(vm.valueExclusionFilter) > 0 {
+			property.Not = &JSONSchema{Pattern: vm.valueExclusionFilter}
+		}
+	} else {
+		if filter, _ := typeFilter(vm.typeName); filter != vm.valueInclusionFilter {
+			property.ValueInclusionFilter = vm.valueInclusionFilter
+		}
+		property.ValueExclusionFilter = vm.valueExclusionFilter
+	}
+
+	property.WriteOnly = vm.sensitive
+	property.Sensitive = vm.sensitive
+	property.EnvVars = vm.environmentVariables
+	property.DependsOn = vm.dependsOn
+	property.Tags = vm.tags
+	property.TargetKey = vm.key
+	property.Group = vm.group
+	property.VisibleIf = vm.visibleIf
+	property.RequiredIf = vm.requiredIf
+	property.RenamedFrom = vm.renamedFrom
+	property.Deprecated = vm.deprecated
+	property.DeprecationMessage = vm.deprecatedMsg
+	return property
+}
+
+// out: the json schema of values of
+//      the given terraform type
+func typeSchema(typeName string) *JSONSchema {
+
+	// the argument of a collection type i.e.
+	// 'string' of 'list(string)'
+	elementType := ""
+	if i := strings.Index(typeName, "("); i >= 0 && strings.HasSuffix(typeName, ")") {
+		elementType = strings.TrimSpace(typeName[i+1 : len(typeName)-1])
+	}
+
+	switch typeKind(typeName) {
+	case "", "string":
+		return &JSONSchema{Type: "string"}
+	case "number":
+		return &JSONSchema{Type: "number"}
+	case "bool":
+		return &JSONSchema{Type: "boolean"}
+	case "list", "set":
+		return &JSONSchema{Type: "array", Items: typeSchema(elementType)}
+	case "tuple":
+		return &JSONSchema{Type: "array"}
+	case "map":
+		return &JSONSchema{Type: "object", AdditionalProperties: typeSchema(elementType)}
+	case "object":
+		return &JSONSchema{Type: "object"}
+	}
+	// any
+	return &JSONSchema{}
+}
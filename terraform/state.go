@@ -0,0 +1,134 @@
+package terraform
+
+/**
+ * Terraform State
+ */
+
+// a resource instance deployed by terraform
+type StateResource struct {
+	Address       string      `json:"address"`
+	ModuleAddress string      `json:"moduleAddress,omitempty"`
+	Mode          string      `json:"mode"`
+	Type          string      `json:"type"`
+	Name          string      `json:"name"`
+	Index         interface{} `json:"index,omitempty"`
+	ProviderName  string      `json:"providerName"`
+
+	// the resource's identifier in the cloud
+	// which is the value of its 'id' attribute
+	ID string `json:"id"`
+
+	Tainted   bool     `json:"tainted"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	Attributes map[string]interface{} `json:"attributes"`
+
+	// structure matching the attributes where
+	// sensitive values have a true leaf value
+	SensitiveValues map[string]interface{} `json:"sensitiveValues,omitempty"`
+}
+
+// deployed state of a terraform configuration
+type State struct {
+	TerraformVersion string `json:"terraformVersion"`
+
+	Outputs   map[string]Output `json:"outputs"`
+	Resources []*StateResource  `json:"resources"`
+}
+
+// terraform's json representation of the
+// state as output by 'terraform show -json'
+type stateJSON struct {
+	TerraformVersion string `json:"terraform_version"`
+
+	Values *struct {
+		Outputs    map[string]Output `json:"outputs"`
+		RootModule stateModuleJSON   `json:"root_module"`
+	} `json:"values"`
+}
+
+type stateModuleJSON struct {
+	Address      string              `json:"address"`
+	Resources    []stateResourceJSON `json:"resources"`
+	ChildModules []stateModuleJSON   `json:"child_modules"`
+}
+
+type stateResourceJSON struct {
+	Address         string                 `json:"address"`
+	Mode            string                 `json:"mode"`
+	Type            string                 `json:"type"`
+	Name            string                 `json:"name"`
+	Index           interface{}            `json:"index"`
+	ProviderName    string                 `json:"provider_name"`
+	Values          map[string]interface{} `json:"values"`
+	SensitiveValues map[string]interface{} `json:"sensitive_values"`
+	DependsOn       []string               `json:"depends_on"`
+	Tainted         bool                   `json:"tainted"`
+}
+
+func newState(state *stateJSON) *State {
+
+	s := &State{
+		TerraformVersion: state.TerraformVersion,
+
+		Outputs:   make(map[string]Output),
+		Resources: []*StateResource{},
+	}
+	if state.Values != nil {
+		if state.Values.Outputs != nil {
+			s.Outputs = state.Values.Outputs
+		}
+		s.addModuleResources(&state.Values.RootModule)
+	}
+	return s
+}
+
+func (s *State) addModuleResources(module *stateModuleJSON) {
+
+	for _, r := range module.Resources {
+
+		resource := &StateResource{
+			Address:       r.Address,
+			ModuleAddress: module.Address,
+			Mode:          r.Mode,
+			Type:          r.Type,
+			Name:          r.Name,
+			Index:         r.Index,
+			ProviderName:  r.ProviderName,
+
+			Tainted:   r.Tainted,
+			DependsOn: r.DependsOn,
+
+			Attributes:      r.Values,
+			SensitiveValues: r.SensitiveValues,
+		}
+		if resource.Attributes == nil {
+			resource.Attributes = make(map[string]interface{})
+		}
+		if id, ok := resource.Attributes["id"].(string); ok {
+			resource.ID = id
+		}
+		s.Resources = append(s.Resources, resource)
+	}
+	for i := range module.ChildModules {
+		s.addModuleResources(&module.ChildModules[i])
+	}
+}
+
+// out: the resource with the given address or nil if
+//      the state does not have such a resource
+func (s *State) Resource(address string) *StateResource {
+
+	for _, r := range s.Resources {
+		if r.Address == address {
+			return r
+		}
+	}
+	return nil
+}
+
+// out: true if the given top-level attribute
+//      has a sensitive value
+func (r *StateResource) IsSensitive(attribute string) bool {
+	return hasSensitiveValues(r.SensitiveValues[attribute])
+}
@@ -0,0 +1,121 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Terraform Variable Types
+ */
+
+// out: the kind of the given type constraint
+//      i.e. "list" for "list(string)"
+func typeKind(typeName string) string {
+
+	kind := strings.TrimSpace(typeName)
+	if i := strings.Index(kind, "("); i >= 0 {
+		kind = strings.TrimSpace(kind[:i])
+	}
+	return kind
+}
+
+// prefix of the input field tag with the
+// terraform type of the field's variable
+const FieldTypeTagPrefix = "type:"
+
+// out: the tags of the input field of a variable of the
+//      given type. the type is added as a tag i.e.
+//      "type:number" to the variable's tags.
+func fieldTags(typeName string, tags []string) []string {
+
+	ft := make([]string, 0, len(tags)+1)
+	ft = append(ft, tags...)
+	if typeName = strings.TrimSpace(typeName); len(typeName) > 0 {
+		ft = append(ft, FieldTypeTagPrefix+typeName)
+	}
+	return ft
+}
+
+// in: tags - the tags of a recipe input field
+// out: the terraform type of the field's variable or an
+//      empty string if the variable does not have a type
+func FieldType(tags []string) string {
+
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, FieldTypeTagPrefix) {
+			return strings.TrimPrefix(tag, FieldTypeTagPrefix)
+		}
+	}
+	return ""
+}
+
+// out: a regex filter input values of the given type
+//      must match and the message shown if a value
+//      does not match. complex values are validated
+//      fully when they are passed to terraform.
+func typeFilter(typeName string) (string, string) {
+
+	switch typeKind(typeName) {
+	case "number":
+		return `^\s*-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?\s*$`,
+			"value must be a number"
+	case "bool":
+		return `^(true|false)$`,
+			"value must be 'true' or 'false'"
+	case "list", "set", "tuple":
+		return `(?s)^\s*\[.*\]\s*$`,
+			fmt.Sprintf("value must be a json encoded %s i.e. [\"a\", \"b\"]", typeName)
+	case "map", "object":
+		return `(?s)^\s*\{.*\}\s*$`,
+			fmt.Sprintf("value must be a json encoded %s i.e. {\"key\": \"value\"}", typeName)
+	}
+	return "", ""
+}
+
+// out: the default value of a variable as a string
+//      that can be given as an input value. complex
+//      values are json encoded.
+func defaultValueLiteral(value interface{}) (string, error) {
+
+	var (
+		err  error
+		data []byte
+	)
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int64, uint, uint64:
+		return fmt.Sprintf("%d", v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case json.Number:
+		return v.String(), nil
+	}
+	if data, err = json.Marshal(value); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// out: true if the given json decoded value
+//      matches the kind of the type constraint
+func matchesTypeKind(typeName string, value interface{}) bool {
+
+	switch typeKind(typeName) {
+	case "list", "set", "tuple":
+		_, ok := value.([]interface{})
+		return ok
+	case "map", "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+)
+
+/**
+ * Terraform Configuration Validation
+ */
+
+// diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// result of validating a terraform configuration
+// as output by 'terraform validate -json'
+type ValidationResult struct {
+	Valid        bool `json:"valid"`
+	ErrorCount   int  `json:"error_count"`
+	WarningCount int  `json:"warning_count"`
+
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+
+// out: the diagnostics with the given severity
+func (v *ValidationResult) DiagnosticsWithSeverity(severity string) []*Diagnostic {
+
+	diagnostics := []*Diagnostic{}
+	for _, d := range v.Diagnostics {
+		if d.Severity == severity {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+func (v *ValidationResult) String() string {
+
+	var (
+		out strings.Builder
+	)
+
+	if v.Valid {
+		out.WriteString("Configuration is valid.")
+	} else {
+		out.WriteString("Configuration is invalid.")
+	}
+	for _, d := range v.Diagnostics {
+		out.WriteString("\n  ")
+		out.WriteString(d.String())
+	}
+	return out.String()
+}
+
+// out: the position of the configuration the
+//      diagnostic refers to i.e. "main.tf:12,3"
+//      or an empty string if it has no range
+func (d *Diagnostic) Position() string {
+	if d.Range == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d,%d", d.Range.Filename, d.Range.Start.Line, d.Range.Start.Column)
+}
+
+func (d *Diagnostic) String() string {
+	if pos := d.Position(); len(pos) > 0 {
+		return fmt.Sprintf("%s: %s (%s)", d.Severity, d.Summary, pos)
+	}
+	return fmt.Sprintf("%s: %s", d.Severity, d.Summary)
+}
@@ -0,0 +1,376 @@
+package terraform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/mevansam/goutils/logger"
+)
+
+/**
+ * Terraform Variable Validation Blocks
+ */
+
+// a validation rule declared via a 'validation'
+// block of a terraform variable declaration
+type VariableValidation struct {
+	// source of the condition expression
+	Condition string
+	// message shown if the condition is false
+	ErrorMessage string
+
+	condition hcl.Expression
+
+	// false if the condition references values other
+	// than the variable or calls functions that are
+	// not available locally. such conditions are only
+	// evaluated by terraform.
+	evaluable bool
+}
+
+// functions available to conditions
+// that are evaluated locally
+var validationFunctions = map[string]function.Function{
+	"can":       tryfunc.CanFunc,
+	"try":       tryfunc.TryFunc,
+	"regex":     stdlib.RegexFunc,
+	"regexall":  stdlib.RegexAllFunc,
+	"contains":  stdlib.ContainsFunc,
+	"length":    lengthFunc,
+	"lower":     stdlib.LowerFunc,
+	"upper":     stdlib.UpperFunc,
+	"trimspace": stdlib.TrimSpaceFunc,
+	"substr":    stdlib.SubstrFunc,
+	"keys":      stdlib.KeysFunc,
+	"values":    stdlib.ValuesFunc,
+}
+
+// terraform's length function which
+// accepts strings as well as collections
+var lengthFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "value",
+			Type:             cty.DynamicPseudoType,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		if args[0].Type() == cty.String {
+			return stdlib.Strlen(args[0])
+		}
+		return stdlib.Length(args[0])
+	},
+})
+
+// in: variableName - the name of the variable the condition validates
+// in: condition - the condition expression i.e. 'length(var.name) > 3'
+// in: errorMessage - the message shown if the condition is false
+// out: a validation rule for the variable
+func NewVariableValidation(
+	variableName,
+	condition,
+	errorMessage string,
+) (*VariableValidation, error) {
+
+	expr, diags := hclsyntax.ParseExpression([]byte(condition), "validation", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid validation condition '%s': %s", condition, diags.Error())
+	}
+	return newVariableValidation(variableName, expr, condition, errorMessage), nil
+}
+
+func newVariableValidation(
+	variableName string,
+	expr hcl.Expression,
+	condition,
+	errorMessage string,
+) *VariableValidation {
+
+	v := &VariableValidation{
+		Condition:    condition,
+		ErrorMessage: errorMessage,
+
+		condition: expr,
+		evaluable: true,
+	}
+
+	// the condition may only reference the variable
+	for _, t := range expr.Variables() {
+		if t.RootName() != "var" || len(t) < 2 {
+			v.evaluable = false
+			break
+		}
+		if attr, ok := t[1].(hcl.TraverseAttr); !ok || attr.Name != variableName {
+			v.evaluable = false
+			break
+		}
+	}
+	// and may only call functions available locally
+	if node, ok := expr.(hclsyntax.Node); ok && v.evaluable {
+		hclsyntax.VisitAll(node, func(n hclsyntax.Node) hcl.Diagnostics {
+			if call, ok := n.(*hclsyntax.FunctionCallExpr); ok {
+				if _, exists := validationFunctions[call.Name]; !exists {
+					v.evaluable = false
+				}
+			}
+			return nil
+		})
+	}
+	if !v.evaluable {
+		logger.DebugMessage(
+			"Validation condition '%s' of variable '%s' cannot be evaluated locally and will be evaluated by terraform.",
+			condition, variableName)
+	}
+	return v
+}
+
+// out: true if the condition can be evaluated locally
+func (v *VariableValidation) IsEvaluable() bool {
+	return v.evaluable
+}
+
+// validates the given value of a variable
+//
+// in: name - the name of the variable
+// in: typeName - the variable's type constraint
+// in: value - the value to validate
+// out: an error with the rule's error message
+//      if the value is not valid
+func (v *VariableValidation) Validate(name, typeName, value string) error {
+
+	var (
+		err error
+		val cty.Value
+	)
+
+	if !v.evaluable {
+		return nil
+	}
+	if val, err = ctyValue(typeName, value); err != nil {
+		return err
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{
+				name: val,
+			}),
+		},
+		Functions: validationFunctions,
+	}
+	result, diags := v.condition.Value(ctx)
+	if diags.HasErrors() {
+		// terraform also fails validation if
+		// the condition cannot be evaluated
+		logger.TraceMessage(
+			"Error evaluating validation condition '%s' of variable '%s': %s",
+			v.Condition, name, diags.Error())
+		return errors.New(v.ErrorMessage)
+	}
+	if result.IsNull() || !result.IsKnown() || result.Type() != cty.Bool {
+		return fmt.Errorf(
+			"validation condition '%s' of variable '%s' did not evaluate to a bool",
+			v.Condition, name)
+	}
+	if result.False() {
+		return errors.New(v.ErrorMessage)
+	}
+	return nil
+}
+
+// validates a value of a variable against the
+// variable's type and all its validation rules
+//
+// out: an error if the value is not of the variable's
+//      type or the error of the first rule the value
+//      does not satisfy
+func ValidateVariable(
+	name,
+	typeName,
+	value string,
+	validations []*VariableValidation,
+) error {
+
+	// the type is checked independently of any value
+	// filter as a filter may accept values of the
+	// wrong type
+	if _, err := typedValue(typeName, value); err != nil {
+		return err
+	}
+	for _, v := range validations {
+		if err := v.Validate(name, typeName, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// out: the given string value as a cty value
+//      of the given terraform type constraint
+func ctyValue(typeName, value string) (cty.Value, error) {
+
+	var (
+		err error
+
+		typed     interface{}
+		jsonValue []byte
+		impliedTy cty.Type
+	)
+
+	if typed, err = typedValue(typeName, value); err != nil {
+		return cty.NilVal, err
+	}
+	if s, ok := typed.(string); ok {
+		return cty.StringVal(s), nil
+	}
+	if jsonValue, err = json.Marshal(typed); err != nil {
+		return cty.NilVal, err
+	}
+	if impliedTy, err = ctyjson.ImpliedType(jsonValue); err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(jsonValue, impliedTy)
+}
+
+// reads the validation blocks of the variables declared
+// in the given terraform files
+//
+// out: validation rules keyed by variable name
+func readVariableValidations(fileNames []string) (map[string][]*VariableValidation, error) {
+
+	var (
+		err error
+
+		src []byte
+	)
+
+	variableSchema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "variable", LabelNames: []string{"name"}},
+		},
+	}
+	validationSchema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "validation"},
+		},
+	}
+	ruleSchema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "condition", Required: true},
+			{Name: "error_message", Required: true},
+		},
+	}
+
+	validations := make(map[string][]*VariableValidation)
+	parser := hclparse.NewParser()
+
+	for _, fileName := range fileNames {
+		if src, err = os.ReadFile(fileName); err != nil {
+			return nil, err
+		}
+		isJSON := strings.HasSuffix(fileName, ".tf.json")
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if isJSON {
+			file, diags = parser.ParseJSON(src, fileName)
+		} else {
+			file, diags = parser.ParseHCL(src, fileName)
+		}
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("error parsing terraform template '%s': %s", fileName, diags.Error())
+		}
+
+		content, _, _ := file.Body.PartialContent(variableSchema)
+		for _, variableBlock := range content.Blocks {
+			name := variableBlock.Labels[0]
+
+			variableContent, _, _ := variableBlock.Body.PartialContent(validationSchema)
+			for _, validationBlock := range variableContent.Blocks {
+
+				rule, diags := validationBlock.Body.Content(ruleSchema)
+				if diags.HasErrors() {
+					return nil, fmt.Errorf(
+						"invalid validation of variable '%s' in '%s': %s",
+						name, fileName, diags.Error())
+				}
+				conditionAttr := rule.Attributes["condition"]
+				messageAttr := rule.Attributes["error_message"]
+
+				// the error message is usually a string literal
+				errorMessage := string(messageAttr.Expr.Range().SliceBytes(src))
+				if message, diags := messageAttr.Expr.Value(nil); !diags.HasErrors() &&
+					message.IsKnown() && !message.IsNull() && message.Type() == cty.String {
+					errorMessage = message.AsString()
+				}
+
+				conditionExpr := conditionAttr.Expr
+				condition := strings.TrimSpace(string(conditionExpr.Range().SliceBytes(src)))
+				if isJSON {
+					if conditionExpr, condition, err = jsonCondition(condition); err != nil {
+						return nil, fmt.Errorf(
+							"invalid validation of variable '%s' in '%s': %s",
+							name, fileName, err.Error())
+					}
+				}
+
+				validations[name] = append(validations[name],
+					newVariableValidation(
+						name,
+						conditionExpr,
+						condition,
+						errorMessage,
+					),
+				)
+			}
+		}
+	}
+	return validations, nil
+}
+
+// conditions in terraform json files are string templates
+// i.e. "${length(var.name) > 3}". they are parsed in native
+// syntax so they are evaluated like those of hcl files.
+//
+// in: literal - the json string literal of the condition
+// out: the condition expression and its source
+func jsonCondition(literal string) (hcl.Expression, string, error) {
+
+	var (
+		err error
+
+		template string
+		expr     hclsyntax.Expression
+		diags    hcl.Diagnostics
+	)
+
+	if err = json.Unmarshal([]byte(literal), &template); err != nil {
+		return nil, "", err
+	}
+	condition := strings.TrimSpace(template)
+	if strings.HasPrefix(condition, "${") && strings.HasSuffix(condition, "}") &&
+		strings.Count(condition, "${") == 1 {
+		condition = strings.TrimSpace(condition[2 : len(condition)-1])
+		expr, diags = hclsyntax.ParseExpression([]byte(condition), "validation", hcl.InitialPos)
+	} else {
+		expr, diags = hclsyntax.ParseTemplate([]byte(template), "validation", hcl.InitialPos)
+	}
+	if diags.HasErrors() {
+		return nil, "", errors.New(diags.Error())
+	}
+	return expr, condition, nil
+}
@@ -0,0 +1,56 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mevansam/goutils/run"
+
+	"github.com/appbricks/cloud-builder/semver"
+)
+
+/**
+ * Terraform CLI Version
+ */
+
+// terraform's json representation of
+// the output of 'terraform version -json'
+type versionJSON struct {
+	TerraformVersion string `json:"terraform_version"`
+	Platform         string `json:"platform"`
+}
+
+// runs 'terraform version -json' using the
+// terraform binary at the given path
+//
+// in: cliPath - path of the terraform binary
+// in: workingDirectory - directory to run the binary in
+// out: the version of the terraform binary
+func GetCLIVersion(cliPath, workingDirectory string) (*semver.Version, error) {
+
+	var (
+		err error
+
+		cli run.CLI
+
+		outputBuffer,
+		errorBuffer bytes.Buffer
+
+		version versionJSON
+	)
+
+	if cli, err = run.NewCLI(cliPath, workingDirectory, &outputBuffer, &errorBuffer); err != nil {
+		return nil, err
+	}
+	if err = cli.RunWithEnv([]string{"version", "-json"}, []string{}); err != nil {
+		return nil, fmt.Errorf(
+			"unable to determine the version of the terraform cli at '%s': %s",
+			cliPath, strings.TrimSpace(errorBuffer.String()))
+	}
+	if err = json.Unmarshal(outputBuffer.Bytes(), &version); err != nil {
+		return nil, err
+	}
+	return semver.ParseVersion(version.TerraformVersion)
+}
@@ -0,0 +1,41 @@
+# @recipe_description: Conditional Variables Test Template
+
+# @accepted_values: wireguard,openvpn
+# @order: 1
+variable "vpn_type" {
+  type        = string
+  default     = "wireguard"
+  description = "Type of VPN to deploy"
+}
+
+# @visible_if: var.vpn_type == "wireguard"
+# @order: 2
+variable "wireguard_port" {
+  type        = number
+  default     = 51820
+  description = "Port of the wireguard service"
+}
+
+# @visible_if: var.vpn_type == "openvpn"
+# @required_if: var.vpn_type == "openvpn"
+# @order: 3
+variable "openvpn_cert" {
+  type        = string
+  default     = ""
+  description = "Certificate of the openvpn service"
+}
+
+# @order: 4
+variable "enable_dns" {
+  type        = bool
+  default     = false
+  description = "Enable the DNS service"
+}
+
+# @required_if: var.enable_dns && var.vpn_type != ""
+# @order: 5
+variable "dns_domain" {
+  type        = string
+  default     = ""
+  description = "Domain of the DNS service"
+}
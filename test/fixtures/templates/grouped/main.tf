@@ -0,0 +1,51 @@
+# @recipe_description: Grouped Variables Test Template
+
+# @group_description: Network: Network of the deployment
+# @group_order: Network: 1
+# @group_description: Credentials: Credentials used to access the deployment
+# @group_order: Credentials: 2
+
+# @order: 1
+variable "name" {
+  type        = string
+  description = "Name of the deployment"
+}
+
+# @group: Credentials
+# @order: 2
+variable "admin_user" {
+  type        = string
+  default     = "admin"
+  description = "Name of the administrator"
+}
+
+# @group: Network
+# @order: 3
+variable "vpc_cidr" {
+  type        = string
+  default     = "10.0.0.0/16"
+  description = "CIDR of the VPC"
+}
+
+# @group: VPN
+# @order: 4
+variable "vpn_type" {
+  type        = string
+  default     = "wireguard"
+  description = "Type of VPN to deploy"
+}
+
+# @group: Credentials
+# @order: 5
+variable "admin_password" {
+  type        = string
+  description = "Password of the administrator"
+}
+
+# @group: Network
+# @order: 6
+variable "subnet_cidr" {
+  type        = string
+  default     = "10.0.1.0/24"
+  description = "CIDR of the subnet"
+}
@@ -0,0 +1,7 @@
+# @recipe_description: JSON Variables Test Template
+
+variable "region" {
+  type        = string
+  default     = "us-east-1"
+  description = "Region to deploy to"
+}
@@ -0,0 +1,44 @@
+# @recipe_description: Annotation Lint Test Template
+# @is_bastion: yes
+
+# @display_name: Name
+# @acepted_values: a,b,c
+# @target_key: true
+# @order: 1
+variable "name" {
+  type        = string
+  default     = ""
+  description = "Name of the deployment"
+}
+
+# @display_name: Region
+# @depends_on: name,zone
+# @sensitive: maybe
+# @order: 1
+variable "region" {
+  type        = string
+  description = "Region of the deployment"
+}
+
+# @display_name: Size
+# @order: first
+# @tags output
+variable "size" {
+  type        = string
+  default     = "small"
+  description = "Size of the deployment"
+}
+
+# @display_name: Ignored
+
+variable "unattached" {
+  type        = string
+  default     = "value"
+  description = "Variable with a detached comment"
+}
+
+# @visible_if: var.size == "large"
+variable "storage" {
+  type        = string
+  description = "Storage of a large deployment"
+}
@@ -0,0 +1,8 @@
+# @recipe_description: Manifest Conflict Test Template
+
+# @display_name: Region
+variable "region" {
+  type        = string
+  default     = "us-east-1"
+  description = "Region to deploy to"
+}
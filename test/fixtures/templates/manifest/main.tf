@@ -0,0 +1,20 @@
+# @resource_instance_list: aws_instance.bastion
+
+# @display_name: Region
+# @order: 1
+variable "region" {
+  type        = string
+  default     = "us-east-1"
+  description = "Region to deploy to"
+}
+
+variable "name" {
+  type        = string
+  description = "Name of the deployment"
+}
+
+variable "instance_type" {
+  type        = string
+  default     = "t3.micro"
+  description = "Instance type"
+}
@@ -0,0 +1,5 @@
+# @recipe_description: Removed Variables Test Template
+
+output "message" {
+  value = "all variables of this template have been removed"
+}
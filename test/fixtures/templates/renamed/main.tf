@@ -0,0 +1,27 @@
+# @recipe_description: Renamed Variables Test Template
+
+variable "name" {
+  type        = string
+  description = "Name of the deployment"
+}
+
+# @renamed_from: region_name
+variable "region" {
+  type        = string
+  default     = "us-east-1"
+  description = "Region to deploy to"
+}
+
+# @renamed_from: instance_size, size
+variable "instance_type" {
+  type        = string
+  default     = "t3.micro"
+  description = "Instance type"
+}
+
+# @deprecated: use instance_type to size the deployment
+variable "legacy_size" {
+  type        = string
+  default     = ""
+  description = "Size of the deployment"
+}
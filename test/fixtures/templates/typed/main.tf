@@ -0,0 +1,47 @@
+# @recipe_description: Typed Variables Test Template
+
+variable "instance_count" {
+  type        = number
+  default     = 2
+  description = "Number of instances"
+}
+
+variable "max_connections" {
+  type        = number
+  default     = 1000000
+  description = "Maximum number of connections"
+}
+
+variable "enable_logging" {
+  type        = bool
+  default     = true
+  description = "Enable logging"
+}
+
+variable "allowed_cidrs" {
+  type        = list(string)
+  default     = ["10.0.0.0/16", "192.168.0.0/24"]
+  description = "CIDRs allowed to access the instances"
+}
+
+variable "tags" {
+  type        = map(string)
+  default     = { owner = "ops" }
+  description = "Tags to add to all resources"
+}
+
+variable "network" {
+  type = object({
+    name = string
+    mtu  = number
+  })
+  description = "Network configuration"
+}
+
+# @value_inclusion_filter: ^[0-9]+$
+# @value_inclusion_filter_message: size must be a whole number
+variable "disk_size" {
+  type        = number
+  default     = 20
+  description = "Disk size in GB"
+}
@@ -0,0 +1,48 @@
+# @recipe_description: Validated Variables Test Template
+
+variable "name" {
+  type        = string
+  description = "Name of the deployment"
+
+  validation {
+    condition     = can(regex("^[a-z][a-z0-9-]*$", var.name))
+    error_message = "The name must start with a letter and contain only lowercase letters, digits and dashes."
+  }
+  validation {
+    condition     = length(var.name) <= 16
+    error_message = "The name must be at most 16 characters."
+  }
+}
+
+variable "instance_type" {
+  type        = string
+  default     = "t3.micro"
+  description = "Instance type"
+
+  validation {
+    condition     = contains(["t3.micro", "t3.small"], var.instance_type)
+    error_message = "The instance type must be one of t3.micro or t3.small."
+  }
+}
+
+variable "allowed_cidrs" {
+  type        = list(string)
+  default     = []
+  description = "CIDRs allowed to access the instances"
+
+  validation {
+    condition     = length(var.allowed_cidrs) <= 2
+    error_message = "At most 2 CIDRs are allowed."
+  }
+}
+
+variable "subnet" {
+  type        = string
+  default     = "10.0.0.0/24"
+  description = "Subnet of the deployment"
+
+  validation {
+    condition     = cidrnetmask(var.subnet) != ""
+    error_message = "The subnet must be a valid CIDR."
+  }
+}
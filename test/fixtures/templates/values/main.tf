@@ -0,0 +1,25 @@
+# @recipe_description: Accepted Values Providers Test Template
+
+# @accepted_values: $iaas_zones:us-east-1
+variable "zone" {
+  type        = string
+  description = "Availability zone"
+}
+
+# @accepted_values: $file:sizes.txt
+variable "size" {
+  type        = string
+  description = "Size of the deployment"
+}
+
+# @accepted_values: $iaas_images
+variable "image" {
+  type        = string
+  description = "Image of the instances"
+}
+
+# @accepted_values: $custom_versions
+variable "version" {
+  type        = string
+  description = "Version to deploy"
+}
@@ -3,9 +3,11 @@ package mocks
 import (
 	"io"
 
+	"github.com/mevansam/goforms/forms"
 	"github.com/mevansam/goutils/run"
 
 	"github.com/appbricks/cloud-builder/cookbook"
+	"github.com/appbricks/cloud-builder/terraform"
 
 	. "github.com/onsi/gomega"
 
@@ -19,12 +21,16 @@ type FakeRecipe struct {
 	recipePath string
 
 	isBastion bool
+
+	hiddenFields map[string]bool
 }
 
 func NewFakeRecipe(cli run.CLI) *FakeRecipe {
 
 	f := &FakeRecipe{
 		cli: cli,
+
+		hiddenFields: make(map[string]bool),
 	}
 	f.InitConfig("Test Recipe Input", "Input form for mock recipe for testing")
 	return f
@@ -104,6 +110,88 @@ func (f *FakeRecipe) GetVariables() []*cookbook.Variable {
 	return variables
 }
 
+func (f *FakeRecipe) SetHidden(name string, hidden bool) {
+	f.hiddenFields[name] = hidden
+}
+
+func (f *FakeRecipe) IsVariableVisible(name string) bool {
+	return !f.hiddenFields[name]
+}
+
+func (f *FakeRecipe) IsVariableRequired(name string) bool {
+
+	if !f.IsVariableVisible(name) {
+		return false
+	}
+	inputForm, _ := f.InputForm()
+	inputField, err := inputForm.GetInputField(name)
+	Expect(err).NotTo(HaveOccurred())
+
+	return !inputField.Optional()
+}
+
+func (f *FakeRecipe) VisibleInputFields() ([]*forms.InputField, error) {
+
+	inputForm, err := f.InputForm()
+	if err != nil {
+		return nil, err
+	}
+	fields := []*forms.InputField{}
+	for _, field := range inputForm.InputFields() {
+		if f.IsVariableVisible(field.Name()) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+func (f *FakeRecipe) ValidateInputs() error {
+	return nil
+}
+
+func (f *FakeRecipe) GetVariableGroups() []*cookbook.VariableGroup {
+	return []*cookbook.VariableGroup{
+		{Variables: f.GetVariables()},
+	}
+}
+
+func (f *FakeRecipe) GetInputFieldGroups() ([]*cookbook.InputFieldGroup, error) {
+
+	inputForm, err := f.InputForm()
+	if err != nil {
+		return nil, err
+	}
+	return []*cookbook.InputFieldGroup{
+		{Fields: inputForm.InputFields()},
+	}, nil
+}
+
+func (f *FakeRecipe) InputSchema() *terraform.JSONSchema {
+
+	inputForm, _ := f.InputForm()
+	schema := terraform.NewInputSchema(f.Name(), f.Description())
+	for _, field := range inputForm.InputFields() {
+		schema.AddProperty(
+			field.Name(),
+			&terraform.JSONSchema{
+				Type:        "string",
+				Title:       field.DisplayName(),
+				Description: field.Description(),
+			},
+			!field.Optional(),
+		)
+	}
+	return schema
+}
+
+func (f *FakeRecipe) MigrationReport() *cookbook.MigrationReport {
+	return &cookbook.MigrationReport{
+		Renamed:    []*cookbook.VariableRename{},
+		Deprecated: []*cookbook.VariableDeprecation{},
+		Removed:    []string{},
+	}
+}
+
 func (f *FakeRecipe) SetBastion() {
 	f.isBastion = true
 }